@@ -27,6 +27,8 @@ var opts struct {
 	IgnoreTls  bool   `short:"i" long:"ignore-tls" description:"Ignores invalid TLS certificates. It is not recomended for production. Use at your own risk" default:"false"`
 	ProcessXsd bool  `short:"x" long:"process-xsd" description:"Process only xsd. it will process the file as xsd or the folder if specified in is-folder" default:"false"`
 	XsdFolder  bool   `short:"f" long:"is-folder" description:"Process only xsd. used by process xsd. It'll go recursively in the folder and process all xsd files" default:"false"`
+	Server     bool   `short:"s" long:"server" description:"Also generates a server interface and an http.Handler per port type" default:"false"`
+	Fake       bool   `long:"fake" description:"Also generates the servers and a fake service per port type, answering with canned replies for tests" default:"false"`
 }
 
 func init() {
@@ -63,7 +65,7 @@ func main() {
 		processXSD(opts.Package,opts.IgnoreTls,opts.XsdFolder,args)
 	}else{
 		log.Printf("Process WSDL")
-		processWSDL(opts.Package,opts.IgnoreTls,opts.OutputFile,args)
+		processWSDL(opts.Package,opts.IgnoreTls,opts.Server,opts.Fake,opts.OutputFile,args)
 	}
 
 
@@ -130,8 +132,16 @@ func main() {
 	log.Println("Done 💩")
 }
 
-func processWSDL(packageOpt string, IgnoreTls bool, outputFile string, args []string){
-	gowsdl, err := gen.NewGoWsdl(args[0], packageOpt, IgnoreTls)
+func processWSDL(packageOpt string, IgnoreTls bool, server bool, fake bool, outputFile string, args []string){
+	var genOpts []gen.GeneratorOption
+	if server {
+		genOpts = append(genOpts, gen.GenerateServer())
+	}
+	if fake {
+		genOpts = append(genOpts, gen.GenerateFake())
+	}
+
+	gowsdl, err := gen.NewGoWsdl(args[0], packageOpt, IgnoreTls, genOpts...)
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -165,6 +175,8 @@ func processWSDL(packageOpt string, IgnoreTls bool, outputFile string, args []st
 	data.Write(gocode["header"])
 	//	data.Write(gocode["types"])
 	data.Write(gocode["operations"])
+	data.Write(gocode["server"])
+	data.Write(gocode["fake"])
 
 	source, err := format.Source(data.Bytes())
 	if err != nil {
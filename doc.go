@@ -21,6 +21,11 @@ Application Options:
 	  -i, --ignore-tls
 		Ignores invalid TLS certificates. It is not recomended for production.
 		Use at your own risk.
+	  -s, --server
+		Also generates a server interface and an http.Handler per port type
+	  --fake
+		Also generates the servers and a fake service per port type, answering
+		with canned replies for tests
 
 
 Help Options:
@@ -29,29 +34,59 @@ Help Options:
 
 Features
 
-Supports only Document/Literal wrapped services, which are WS-I (http://ws-i.org/) compliant.
+Supports Document/Literal wrapped services, which are WS-I (http://ws-i.org/) compliant,
+as well as RPC/Literal and RPC/Encoded ones: RPC style operations get a request and a
+response struct holding their message parts.
 
 Attempts to generate idiomatic Go code as much as possible.
 
+Supports messages with several parts.
+
+Generates typed request and response headers from the soap:header bindings of the
+operations, including soap:headerfault.
+
 Generates Go code in parallel: types, operations and soap proxy.
 
-Supports WSDL 1.1, XML Schema 1.0, SOAP 1.1.
+Generates a <PortType>Interface per port type, implemented by the generated client,
+to mock it in tests.
+
+Optionally generates servers: a <PortType>Server interface per port type and a
+New<PortType>Handler http.Handler dispatching the requests on their SOAPAction or
+body element to its implementation, errors being sent as SOAP faults.
+
+Optionally generates fake services for tests: a Fake<PortType> answering with the
+responses or faults programmed per operation and recording the requests it receives,
+served by NewFake<PortType>Server on an httptest.Server.
+
+Supports WSDL 1.1, XML Schema 1.0, SOAP 1.1 and SOAP 1.2.
 
 Resolves external XML Schemas recursively, up to 5 recursions.
 
+Resolves type and element references by namespace, following the xmlns
+declarations of each schema and of the WSDL.
+
 Supports providing WSDL HTTP URL as well as a local WSDL file.
 
-Not supported
+Supports WS-Security UsernameToken (PasswordText and PasswordDigest) and Timestamp headers.
+
+Supports WS-Security X.509 signatures of the requests, with verification of signed responses.
+
+Supports WS-Addressing headers for bindings using WS-Addressing.
 
-Setting SOAP headers.
+Supports MTOM/XOP binary attachments for xs:base64Binary values.
 
-SOAP 1.2 and HTTP port bindings.
+Supports SOAP with Attachments (multipart/related) responses, their parts are
+available through SoapHeader.Attachments or, for calls made without a header,
+through ContextWithAttachments.
 
-WS-Security.
+Records calls to a directory and replays them later without the network, see the
+WithRecording and WithReplay client options.
 
-WS-Addressing.
+Not supported
+
+HTTP port bindings.
 
-MTOM binary attachments.
+WS-Security encryption.
 
 UDDI.
 
@@ -63,8 +98,6 @@ If WSDL file is local, resolve external XML schemas locally too instead of faili
 
 Resolve XSD element references.
 
-Support for generating namespaces.
-
 Make code generation agnostic so generating code to other programming languages is feasible through plugins.
 
 */
@@ -0,0 +1,161 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ChoiceBranch is a branch of an xs:choice of a generated type: its name in
+// the schema and the values of the fields holding it. A branch is set when
+// one of its fields isn't the zero value.
+type ChoiceBranch struct {
+	Name   string
+	Fields []interface{}
+}
+
+// ChoiceError is returned when more than one branch of an xs:choice is set.
+type ChoiceError struct {
+	Type     string
+	Branches []string
+}
+
+func (e *ChoiceError) Error() string {
+	return fmt.Sprintf("%s: only one of %s may be set", e.Type, strings.Join(e.Branches, ", "))
+}
+
+// ValidateChoice returns a *ChoiceError when more than one of the branches of
+// an xs:choice of the generated type typeName is set.
+func ValidateChoice(typeName string, branches ...ChoiceBranch) error {
+	var set []string
+	for _, branch := range branches {
+		for _, field := range branch.Fields {
+			if !isZeroValue(reflect.ValueOf(field)) {
+				set = append(set, branch.Name)
+				break
+			}
+		}
+	}
+	if len(set) > 1 {
+		return &ChoiceError{Type: typeName, Branches: set}
+	}
+	return nil
+}
+
+func isZeroValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+type validator interface {
+	Validate() error
+}
+
+// Validate calls the Validate method of v, if any, and of the values it
+// holds, ie. of the generated types with choices, and returns the first
+// error. Clients validate requests before sending them and servers before
+// serving them.
+func Validate(v interface{}) error {
+	return validateValue(reflect.ValueOf(v))
+}
+
+func validateValue(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return validateValue(v.Elem())
+	case reflect.Struct:
+		if v.CanAddr() {
+			if val, ok := v.Addr().Interface().(validator); ok {
+				if err := val.Validate(); err != nil {
+					return err
+				}
+			}
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			if err := validateValue(v.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		switch v.Type().Elem().Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Struct, reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				if err := validateValue(v.Index(i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// MarshalChoice encodes an occurrence of a repeating xs:choice, item being a
+// pointer to the generated struct holding its branches: the elements of its
+// fields which are set are written without enclosing element, so that the
+// occurrences keep their document order.
+func MarshalChoice(e *xml.Encoder, item interface{}) error {
+	v := reflect.ValueOf(item).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name, ok := choiceFieldName(v.Type().Field(i))
+		if !ok || isZeroValue(v.Field(i)) {
+			continue
+		}
+		if err := e.EncodeElement(v.Field(i).Interface(), xml.StartElement{Name: name}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnmarshalChoice decodes the element start of a repeating xs:choice into the
+// field of item named after it, item being a pointer to the generated struct
+// holding its branches. Unknown elements are skipped.
+func UnmarshalChoice(d *xml.Decoder, start xml.StartElement, item interface{}) error {
+	v := reflect.ValueOf(item).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name, ok := choiceFieldName(v.Type().Field(i))
+		if !ok || name.Local != start.Name.Local || (name.Space != "" && name.Space != start.Name.Space) {
+			continue
+		}
+		return d.DecodeElement(v.Field(i).Addr().Interface(), &start)
+	}
+	return d.Skip()
+}
+
+// Returns the element name of a field of a choice item, from its xml tag.
+func choiceFieldName(field reflect.StructField) (xml.Name, bool) {
+	if field.PkgPath != "" {
+		return xml.Name{}, false
+	}
+	tag := field.Tag.Get("xml")
+	if i := strings.Index(tag, ","); i >= 0 {
+		tag = tag[:i]
+	}
+	if tag == "-" {
+		return xml.Name{}, false
+	}
+	if tag == "" {
+		return xml.Name{Local: field.Name}, true
+	}
+	if i := strings.LastIndex(tag, " "); i >= 0 {
+		return xml.Name{Space: tag[:i], Local: tag[i+1:]}, true
+	}
+	return xml.Name{Local: tag}, true
+}
@@ -0,0 +1,141 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// Written like the types generated for choices.wsdl.
+type payment struct {
+	XMLName   xml.Name         `xml:"http://example.com/payments Pay"`
+	Card      *card            `xml:"http://example.com/payments Card,omitempty"`
+	Iban      string           `xml:"http://example.com/payments Iban,omitempty"`
+	Bic       string           `xml:"http://example.com/payments Bic,omitempty"`
+	Reference string           `xml:"http://example.com/payments Reference,omitempty"`
+	Choice    []*paymentChoice `xml:",any"`
+}
+
+func (t *payment) Validate() error {
+	return ValidateChoice("Pay",
+		ChoiceBranch{Name: "Card", Fields: []interface{}{t.Card}},
+		ChoiceBranch{Name: "(Iban, Bic)", Fields: []interface{}{t.Iban, t.Bic}},
+	)
+}
+
+type card struct {
+	Number string `xml:"http://example.com/payments Number,omitempty"`
+	Token  string `xml:"http://example.com/payments Token,omitempty"`
+}
+
+func (t *card) Validate() error {
+	return ValidateChoice("Card",
+		ChoiceBranch{Name: "Number", Fields: []interface{}{t.Number}},
+		ChoiceBranch{Name: "Token", Fields: []interface{}{t.Token}},
+	)
+}
+
+type paymentChoice struct {
+	Note string   `xml:"http://example.com/payments Note,omitempty"`
+	Tags []string `xml:"http://example.com/payments Tag,omitempty"`
+}
+
+func (c *paymentChoice) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return MarshalChoice(e, c)
+}
+
+func (c *paymentChoice) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return UnmarshalChoice(d, start, c)
+}
+
+func (c *paymentChoice) Validate() error {
+	return ValidateChoice("PayChoice",
+		ChoiceBranch{Name: "Note", Fields: []interface{}{c.Note}},
+		ChoiceBranch{Name: "Tag", Fields: []interface{}{c.Tags}},
+	)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		err   error
+	}{
+		{&payment{Iban: "NL01", Bic: "BANK"}, nil},
+		{&payment{Card: &card{Number: "4111"}}, nil},
+		{&payment{Card: &card{}, Bic: "BANK"}, &ChoiceError{Type: "Pay", Branches: []string{"Card", "(Iban, Bic)"}}},
+		{&payment{Card: &card{Number: "4111", Token: "t"}}, &ChoiceError{Type: "Card", Branches: []string{"Number", "Token"}}},
+		{&payment{Choice: []*paymentChoice{{Note: "a"}, {Tags: []string{"b"}}}}, nil},
+		{&payment{Choice: []*paymentChoice{{Note: "a", Tags: []string{"b"}}}}, &ChoiceError{Type: "PayChoice", Branches: []string{"Note", "Tag"}}},
+		{[]payment{{}, {Iban: "NL01", Card: &card{}}}, &ChoiceError{Type: "Pay", Branches: []string{"Card", "(Iban, Bic)"}}},
+		{nil, nil},
+	}
+
+	for _, test := range tests {
+		err := Validate(test.value)
+		if !reflect.DeepEqual(err, test.err) {
+			t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", err, test.err)
+		}
+	}
+}
+
+func TestChoiceDocumentOrder(t *testing.T) {
+	p := &payment{
+		Reference: "r1",
+		Choice: []*paymentChoice{
+			{Note: "first"},
+			{Tags: []string{"urgent"}},
+			{Note: "second"},
+		},
+	}
+
+	data, err := xml.Marshal(p)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	want := `<Pay xmlns="http://example.com/payments">` +
+		`<Reference xmlns="http://example.com/payments">r1</Reference>` +
+		`<Note xmlns="http://example.com/payments">first</Note>` +
+		`<Tag xmlns="http://example.com/payments">urgent</Tag>` +
+		`<Note xmlns="http://example.com/payments">second</Note></Pay>`
+	if string(data) != want {
+		t.Errorf("incorrect result\ngot:  %s\nwant: %s", data, want)
+	}
+
+	got := &payment{}
+	if err := xml.Unmarshal(data, got); err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	got.XMLName = xml.Name{}
+	if !reflect.DeepEqual(got, p) {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", got, p)
+	}
+}
+
+func TestSoapCallValidatesRequest(t *testing.T) {
+	called := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer ts.Close()
+
+	client := NewSoapClient(ts.URL, false, WithValidation())
+	err := client.Call("", &payment{Card: &card{}, Iban: "NL01"}, &payment{}, nil, nil)
+	if _, ok := err.(*ChoiceError); !ok {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", err, &ChoiceError{})
+	}
+	if called {
+		t.Errorf("invalid request was sent")
+	}
+
+	// Requests are only validated by clients created WithValidation.
+	client = NewSoapClient(ts.URL, false)
+	client.Call("", &payment{Card: &card{}, Iban: "NL01"}, &payment{}, nil, nil)
+	if !called {
+		t.Errorf("request was not sent")
+	}
+}
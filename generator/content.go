@@ -0,0 +1,182 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Resolves the model groups referenced by the content models being generated,
+// implemented by GoWsdl and GoXsd.
+type groupResolver interface {
+	findGroup(ref string) *schemaDecl
+	pushSchemaScope(schema *XsdSchema) string
+	popSchemaScope() string
+}
+
+// Choices of the content model of a complex type.
+type contentChoices struct {
+	groups groupResolver
+	// Choices of which a single branch may be set, checked by the generated
+	// Validate method.
+	Exclusive []*XsdCompositor
+	// First repeating choice outside of other repeating compositors, its
+	// occurrences are kept in document order in a slice of choice items,
+	// OrderedField, encoding/xml decoding the unknown elements of a struct
+	// into a single field.
+	Ordered      *XsdCompositor
+	OrderedField string
+	// Other repeating choices outside of repeating compositors, whose
+	// elements are generated as slices.
+	flattened []*XsdCompositor
+}
+
+// Branch of a choice and the struct fields holding it.
+type choiceBranch struct {
+	Name   string
+	Fields []string
+}
+
+func newContentChoices(groups groupResolver, content *XsdCompositor) *contentChoices {
+	c := &contentChoices{groups: groups}
+	if content == nil {
+		return c
+	}
+	c.add(content, false)
+
+	if c.Ordered != nil {
+		// Named Choice, unless an element of the content is.
+		taken := make(map[string]bool)
+		for _, field := range c.fields(content) {
+			taken[field] = true
+		}
+		c.OrderedField = "Choice"
+		for i := 2; taken[c.OrderedField]; i++ {
+			c.OrderedField = "Choice" + strconv.Itoa(i)
+		}
+	}
+	for _, choice := range c.flattened {
+		var names []string
+		for _, el := range choice.elements() {
+			names = append(names, elementXsdName(el))
+		}
+		Log.Warn("Repeating choice generated as slices, the order of its elements is lost", "elements", strings.Join(names, ", "))
+	}
+	return c
+}
+
+// Adds the choices of compositor, repeated when one of its ancestors repeats.
+func (c *contentChoices) add(compositor *XsdCompositor, repeated bool) {
+	repeats := isArrayElement(compositor.MaxOccurs)
+	if compositor.Kind == "choice" && !repeated {
+		if !repeats && len(compositor.Particles) > 1 {
+			c.Exclusive = append(c.Exclusive, compositor)
+		} else if repeats && c.Ordered == nil {
+			c.Ordered = compositor
+			return
+		} else if repeats {
+			c.flattened = append(c.flattened, compositor)
+		}
+	}
+	for _, p := range compositor.Particles {
+		switch {
+		case p.Compositor != nil:
+			c.add(p.Compositor, repeated || repeats)
+		case p.Group != nil:
+			c.inGroup(p.Group, func(content *XsdCompositor) {
+				c.add(content, repeated || repeats || isArrayElement(p.Group.MaxOccurs))
+			})
+		}
+	}
+}
+
+// Calls f with the content of the group referenced by ref, in the scope of
+// its schema.
+func (c *contentChoices) inGroup(ref *XsdGroup, f func(content *XsdCompositor)) {
+	decl := c.groups.findGroup(ref.Ref)
+	if decl == nil || decl.Group.Content() == nil {
+		return
+	}
+	c.groups.pushSchemaScope(decl.Schema)
+	defer c.groups.popSchemaScope()
+	f(decl.Group.Content())
+}
+
+// Reports whether compositor is the repeating choice kept in a slice of
+// choice items.
+func (c *contentChoices) IsOrdered(compositor *XsdCompositor) bool {
+	return compositor == c.Ordered
+}
+
+// Returns the branches of choice.
+func (c *contentChoices) Branches(choice *XsdCompositor) []choiceBranch {
+	var branches []choiceBranch
+	for _, p := range choice.Particles {
+		switch {
+		case p.Element != nil:
+			branches = append(branches, choiceBranch{
+				Name:   elementXsdName(p.Element),
+				Fields: []string{elementFieldName(p.Element)},
+			})
+		case p.Compositor != nil:
+			var names []string
+			for _, el := range p.Compositor.elements() {
+				names = append(names, elementXsdName(el))
+			}
+			branches = append(branches, choiceBranch{
+				Name:   "(" + strings.Join(names, ", ") + ")",
+				Fields: c.fields(p.Compositor),
+			})
+		case p.Group != nil:
+			branches = append(branches, choiceBranch{
+				Name:   stripns(p.Group.Ref),
+				Fields: c.groupFields(p.Group),
+			})
+		}
+	}
+	return branches
+}
+
+// Returns the struct fields holding the particles of compositor.
+func (c *contentChoices) fields(compositor *XsdCompositor) []string {
+	if c.IsOrdered(compositor) {
+		return []string{c.OrderedField}
+	}
+	var fields []string
+	for _, p := range compositor.Particles {
+		switch {
+		case p.Element != nil:
+			fields = append(fields, elementFieldName(p.Element))
+		case p.Compositor != nil:
+			fields = append(fields, c.fields(p.Compositor)...)
+		case p.Group != nil:
+			fields = append(fields, c.groupFields(p.Group)...)
+		}
+	}
+	return fields
+}
+
+// Returns the struct fields holding the content of the group referenced by
+// ref, inlined in the struct.
+func (c *contentChoices) groupFields(ref *XsdGroup) []string {
+	var fields []string
+	c.inGroup(ref, func(content *XsdCompositor) {
+		fields = c.fields(content)
+	})
+	return fields
+}
+
+// Returns the name of an element in the schema.
+func elementXsdName(el *XsdElement) string {
+	if el.Ref != "" {
+		return stripns(el.Ref)
+	}
+	return el.Name
+}
+
+// Returns the name of the struct field generated for an element.
+func elementFieldName(el *XsdElement) string {
+	return makePublic(replaceReservedWords(elementXsdName(el)))
+}
@@ -0,0 +1,87 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Fake holds the canned replies of the operations of a generated fake server
+// and the requests it received. Its zero value is ready to use.
+type Fake struct {
+	mu       sync.Mutex
+	replies  map[string]*fakeReply
+	requests map[string][]FakeRequest
+}
+
+type fakeReply struct {
+	response       interface{}
+	responseHeader interface{}
+	err            error
+}
+
+// FakeRequest is a request received by a fake server, decoded into the
+// request and request header types of its operation.
+type FakeRequest struct {
+	Request       interface{}
+	RequestHeader interface{}
+}
+
+// Reply answers every following request of operation with response and
+// responseHeader, which may be nil.
+func (f *Fake) Reply(operation string, response, responseHeader interface{}) {
+	f.setReply(operation, &fakeReply{response: response, responseHeader: responseHeader})
+}
+
+// Fail answers every following request of operation with err, a *SoapFault
+// or a typed fault being sent as it is and other errors as server faults.
+func (f *Fake) Fail(operation string, err error) {
+	f.setReply(operation, &fakeReply{err: err})
+}
+
+func (f *Fake) setReply(operation string, reply *fakeReply) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.replies == nil {
+		f.replies = map[string]*fakeReply{}
+	}
+	f.replies[operation] = reply
+}
+
+// Requests returns the requests of operation received so far, in order.
+func (f *Fake) Requests(operation string) []FakeRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]FakeRequest(nil), f.requests[operation]...)
+}
+
+// Reset forgets the replies and the received requests.
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.replies = nil
+	f.requests = nil
+}
+
+// Serve records a request of operation and returns its canned reply. Requests
+// of operations without reply get a server fault.
+func (f *Fake) Serve(operation string, request, requestHeader interface{}) (response, responseHeader interface{}, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.requests == nil {
+		f.requests = map[string][]FakeRequest{}
+	}
+	f.requests[operation] = append(f.requests[operation], FakeRequest{Request: request, RequestHeader: requestHeader})
+
+	reply := f.replies[operation]
+	if reply == nil {
+		return nil, nil, &SoapFault{Faultcode: "Server", Faultstring: fmt.Sprintf("no reply for operation %s", operation)}
+	}
+	return reply.response, reply.responseHeader, reply.err
+}
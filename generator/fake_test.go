@@ -0,0 +1,67 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func newFakeEchoHandler(fake *Fake) *httptest.Server {
+	return httptest.NewServer(NewSoapHandler([]*SoapOperation{{
+		Name:   "Echo",
+		Action: "urn:echo/Echo",
+		Serve: func(ctx context.Context, header, body string) (interface{}, interface{}, error) {
+			request := &echoRequest{}
+			if err := UnmarshalContent(body, request); err != nil {
+				return nil, nil, NewClientFault(err)
+			}
+			return fake.Serve("Echo", request, nil)
+		},
+	}}))
+}
+
+func TestFake(t *testing.T) {
+	fake := &Fake{}
+	ts := newFakeEchoHandler(fake)
+	defer ts.Close()
+	client := NewSoapClient(ts.URL, false)
+
+	err := client.Call("urn:echo/Echo", &echoRequest{Text: "first"}, &echoResponse{}, nil, nil)
+	if fault, ok := err.(*SoapFault); !ok || fault.Faultstring != "no reply for operation Echo" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", err, &SoapFault{Faultstring: "no reply for operation Echo"})
+	}
+
+	fake.Reply("Echo", &echoResponse{Text: "canned"}, nil)
+	response := &echoResponse{}
+	if err := client.Call("urn:echo/Echo", &echoRequest{Text: "second"}, response, nil, nil); err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	if response.Text != "canned" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", response.Text, "canned")
+	}
+
+	fake.Fail("Echo", errors.New("unavailable"))
+	err = client.Call("urn:echo/Echo", &echoRequest{Text: "third"}, &echoResponse{}, nil, nil)
+	if fault, ok := err.(*SoapFault); !ok || fault.Faultstring != "unavailable" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", err, &SoapFault{Faultstring: "unavailable"})
+	}
+
+	var got []string
+	for _, r := range fake.Requests("Echo") {
+		got = append(got, r.Request.(*echoRequest).Text)
+	}
+	want := []string{"first", "second", "third"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	fake.Reset()
+	if requests := fake.Requests("Echo"); len(requests) != 0 {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", requests, []FakeRequest(nil))
+	}
+}
@@ -0,0 +1,78 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+var fakeTmpl = `
+{{range .}}
+{{if hasSoapBinding .Name}}
+	{{$portTypeName := .Name}}
+	{{$portType := .Name | makePublic}}
+	// Fake{{$portType}} is a fake {{.Name}} service for tests, answering with the
+	// replies programmed per operation and recording the requests it receives.
+	// Its zero value is ready to use, see NewFake{{$portType}}Server.
+	type Fake{{$portType}} struct {
+		fake gowsdl.Fake
+	}
+
+	// NewFake{{$portType}}Server starts an httptest.Server serving fake, whose URL is
+	// the one to give to New{{$portType}}. The caller closes it.
+	func NewFake{{$portType}}Server(fake *Fake{{$portType}}) *httptest.Server {
+		return httptest.NewServer(New{{$portType}}Handler(fake))
+	}
+
+	{{range .Operations}}
+		{{$sig := operationSignature . $portTypeName}}
+		{{$method := $sig.Method}}
+		// Reply{{$method}} answers the following {{.Name}} requests with response{{if ne $sig.ResponseHeader ""}}
+		// and responseHeader{{end}}.
+		func (f *Fake{{$portType}}) Reply{{$method}}(response {{$sig.Response}}{{if ne $sig.ResponseHeader ""}}, responseHeader {{$sig.ResponseHeader}}{{end}}) {
+			f.fake.Reply({{printf "%q" .Name}}, response, {{if ne $sig.ResponseHeader ""}}responseHeader{{else}}nil{{end}})
+		}
+
+		// Fail{{$method}} answers the following {{.Name}} requests with err, either a
+		// *gowsdl.SoapFault, a typed fault or any error sent as a server fault.
+		func (f *Fake{{$portType}}) Fail{{$method}}(err error) {
+			f.fake.Fail({{printf "%q" .Name}}, err)
+		}
+
+		{{if ne $sig.Request ""}}
+		// {{$method}}Requests returns the {{.Name}} requests received so far.
+		func (f *Fake{{$portType}}) {{$method}}Requests() []{{$sig.Request}} {
+			var requests []{{$sig.Request}}
+			for _, r := range f.fake.Requests({{printf "%q" .Name}}) {
+				requests = append(requests, r.Request.({{$sig.Request}}))
+			}
+			return requests
+		}
+		{{end}}
+
+		{{if ne $sig.RequestHeader ""}}
+		// {{$method}}RequestHeaders returns the headers of the {{.Name}} requests
+		// received so far.
+		func (f *Fake{{$portType}}) {{$method}}RequestHeaders() []{{$sig.RequestHeader}} {
+			var headers []{{$sig.RequestHeader}}
+			for _, r := range f.fake.Requests({{printf "%q" .Name}}) {
+				headers = append(headers, r.RequestHeader.({{$sig.RequestHeader}}))
+			}
+			return headers
+		}
+		{{end}}
+
+		func (f *Fake{{$portType}}) {{$method}}(ctx context.Context, {{if ne $sig.Request ""}}request {{$sig.Request}}, {{end}}{{if ne $sig.RequestHeader ""}}requestHeader {{$sig.RequestHeader}}{{end}}) ({{$sig.Response}}, {{if ne $sig.ResponseHeader ""}}{{$sig.ResponseHeader}}, {{end}}error) {
+			response, {{if ne $sig.ResponseHeader ""}}responseHeader{{else}}_{{end}}, err := f.fake.Serve({{printf "%q" .Name}}, {{if ne $sig.Request ""}}request{{else}}nil{{end}}, {{if ne $sig.RequestHeader ""}}requestHeader{{else}}nil{{end}})
+			if err != nil {
+				return nil, {{if ne $sig.ResponseHeader ""}}nil, {{end}}err
+			}
+			r, _ := response.({{$sig.Response}})
+			{{if ne $sig.ResponseHeader ""}}
+			h, _ := responseHeader.({{$sig.ResponseHeader}})
+			return r, h, nil
+			{{else}}
+			return r, nil
+			{{end}}
+		}
+	{{end}}
+{{end}}
+{{end}}
+`
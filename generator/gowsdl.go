@@ -30,13 +30,18 @@ const maxRecursion uint8 = 100
 type GoWsdl struct {
 	file, pkg             string
 	ignoreTls             bool
+	server                bool
+	fake                  bool
 	wsdl                  *Wsdl
 	resolvedXsdExternals  map[string]*XsdSchema
 	importsNeeded		  map[string]bool
 	currentRecursionLevel uint8
 	processedComplexTypes map[string]map[string]bool
 	processedSimpleTypes  map[string]map[string]bool
+	processedFaults       map[xml.Name]bool
 	currentSchema         *XsdSchema
+	symbols               *symbolTable
+	schemaScopes          []*XsdSchema
 }
 
 type HeaderElements struct {
@@ -44,6 +49,7 @@ type HeaderElements struct {
 	PkgBase					string
 	ImportsNeeded			map[string]bool
 	ResolvedXsdExternals  	map[string]*XsdSchema
+	Fake                    bool
 }
 
 var cacheDir = filepath.Join(os.TempDir(), "gowsdl-cache")
@@ -85,7 +91,27 @@ func downloadFile(url string, ignoreTls bool) ([]byte, error) {
 	return data, nil
 }
 
-func NewGoWsdl(file, pkg string, ignoreTls bool) (*GoWsdl, error) {
+// GeneratorOption configures the code generated by a GoWsdl.
+type GeneratorOption func(*GoWsdl)
+
+// GenerateServer also generates, for every port type, a server interface to
+// implement and an http.Handler serving it, in gocode["server"].
+func GenerateServer() GeneratorOption {
+	return func(g *GoWsdl) {
+		g.server = true
+	}
+}
+
+// GenerateFake generates the servers along with, for every port type, a fake
+// service answering with canned replies for tests, in gocode["fake"].
+func GenerateFake() GeneratorOption {
+	return func(g *GoWsdl) {
+		g.server = true
+		g.fake = true
+	}
+}
+
+func NewGoWsdl(file, pkg string, ignoreTls bool, opts ...GeneratorOption) (*GoWsdl, error) {
 	file = strings.TrimSpace(file)
 	if file == "" {
 		Log.Crit("WSDL file is required to generate Go proxy")
@@ -97,16 +123,21 @@ func NewGoWsdl(file, pkg string, ignoreTls bool) (*GoWsdl, error) {
 		pkg = "myservice"
 	}
 
-	return &GoWsdl{
+	g := &GoWsdl{
 		file:      file,
 		pkg:       pkg,
 		ignoreTls: ignoreTls,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g, nil
 }
 
 func (g *GoWsdl) Start() (map[string][]byte, map[string][]byte, error) {
 	gocode := make(map[string][]byte)
 	var gotypes map[string][]byte
+	var server, fake []byte
 
 	err := g.unmarshal()
 	if err != nil {
@@ -139,8 +170,41 @@ func (g *GoWsdl) Start() (map[string][]byte, map[string][]byte, error) {
 		}
 	}()
 
+	if g.server {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+
+			server, err = g.genServer()
+			if err != nil {
+				Log.Error("genServer", "error", err)
+			}
+		}()
+	}
+
+	if g.fake {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+
+			fake, err = g.genFake()
+			if err != nil {
+				Log.Error("genFake", "error", err)
+			}
+		}()
+	}
+
 	wg.Wait()
 
+	if server != nil {
+		gocode["server"] = server
+	}
+	if fake != nil {
+		gocode["fake"] = fake
+	}
+
 	gocode["header"], err = g.genHeader()
 	if err != nil {
 		Log.Error("genHeader", "error", err)
@@ -177,6 +241,10 @@ func (g *GoWsdl) unmarshal() error {
 
 	//	spew.Dump(g.wsdl.Types.Schemas)
 
+	for _, schema := range g.wsdl.Types.Schemas {
+		schema.inheritNamespaces(g.wsdl.Xmlns)
+	}
+
 	for _, schema := range g.wsdl.Types.Schemas {
 		err = g.resolveXsdExternals(schema, parsedUrl)
 		if err != nil {
@@ -184,11 +252,19 @@ func (g *GoWsdl) unmarshal() error {
 		}
 	}
 
+	g.symbols = newSchemasSymbolTable("basetypes", g.wsdl.Types.Schemas, g.resolvedXsdExternals)
+	g.symbols.addMessages(g.wsdl.TargetNamespace, g.wsdl.Messages)
+
 	return nil
 }
 
 func (g *GoWsdl) resolveXsdExternals(schema *XsdSchema, url *url.URL) error {
 	for _, impor := range schema.Imports {
+		// Imports of well known namespaces, ie. the SOAP encoding of RPC/encoded
+		// services, have no location.
+		if impor.SchemaLocation == "" {
+			continue
+		}
 		location, err := url.Parse(impor.SchemaLocation)
 		if err != nil {
 			return err
@@ -256,6 +332,12 @@ func (g *GoWsdl) resolveXsdExternals(schema *XsdSchema, url *url.URL) error {
 			return err
 		}
 
+		// Included schemas without target namespace take the one of the
+		// including schema.
+		if newschema.TargetNamespace == "" {
+			newschema.TargetNamespace = schema.TargetNamespace
+		}
+
 		//		g.wsdl.Types.Schemas = append(g.wsdl.Types.Schemas, newschema)
 
 		if g.resolvedXsdExternals == nil {
@@ -284,6 +366,24 @@ func (g *GoWsdl) genTypes() (map[string][]byte, error) {
 		"toGoUnionType":        toGoUnionType,
 		"isBaseType":			isBaseType,
 		"findType":             g.findType,
+		"qualifiedName":        g.qualifiedName,
+		"findGroup":            g.findGroup,
+		"findAttributeGroup":   g.findAttributeGroup,
+		"pushSchemaScope":      g.pushSchemaScope,
+		"popSchemaScope":       g.popSchemaScope,
+		"elementName":          g.elementName,
+		"attributeName":        g.attributeName,
+		"contentChoices":       g.contentChoices,
+		"ancestors":            g.ancestors,
+		"isPolymorphic":        g.isPolymorphic,
+		"typeName":             g.typeName,
+		"elementTypeName":      g.elementTypeName,
+		"typeIsElement":        g.typeIsElement,
+		"polymorphicType":      g.polymorphicType,
+		"xsiTypes":             g.xsiTypes,
+		"isSoapArrayBase":      g.isSoapArrayBase,
+		"replaceStar":          replaceStar,
+		"elementList":          elementList,
 		"stripns":              stripns,
 		"replaceReservedWords": replaceReservedWords,
 		"processComplexType" :  g.processComplexType,
@@ -432,6 +532,19 @@ func (g *GoWsdl) genOperations() ([]byte, error) {
 		"findType":             g.findMessageType,
 		"findSoapAction":       g.findSoapAction,
 		"findServiceAddress":   g.findServiceAddress,
+		"hasSoapBinding":       g.hasSoapBinding,
+		"isSoap12":             g.isSoap12,
+		"usesAddressing":       g.usesAddressing,
+		"findAddressingAction": g.findAddressingAction,
+		"operationParts":       g.operationParts,
+		"operationHeaders":     g.operationHeaders,
+		"operationSignature":   g.operationSignature,
+		"dictValues":           dictValues,
+		"typedFaults":          g.typedFaults,
+		"findFaultType":        g.findFaultType,
+		"findFaultElement":     g.findFaultElement,
+		"faultTypeName":        faultTypeName,
+		"processFault":         g.processFault,
 		"replaceStar":			replaceStar,
 	}
 
@@ -445,6 +558,42 @@ func (g *GoWsdl) genOperations() ([]byte, error) {
 	return data.Bytes(), nil
 }
 
+func (g *GoWsdl) genServer() ([]byte, error) {
+	funcMap := template.FuncMap{
+		"makePublic":         makePublic,
+		"replaceStar":        replaceStar,
+		"hasSoapBinding":     g.hasSoapBinding,
+		"isSoap12":           g.isSoap12,
+		"operationSignature": g.operationSignature,
+	}
+
+	data := new(bytes.Buffer)
+	tmpl := template.Must(template.New("server").Funcs(funcMap).Parse(serverTmpl))
+	err := tmpl.Execute(data, g.wsdl.PortTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.Bytes(), nil
+}
+
+func (g *GoWsdl) genFake() ([]byte, error) {
+	funcMap := template.FuncMap{
+		"makePublic":         makePublic,
+		"hasSoapBinding":     g.hasSoapBinding,
+		"operationSignature": g.operationSignature,
+	}
+
+	data := new(bytes.Buffer)
+	tmpl := template.Must(template.New("fake").Funcs(funcMap).Parse(fakeTmpl))
+	err := tmpl.Execute(data, g.wsdl.PortTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.Bytes(), nil
+}
+
 func (g *GoWsdl) genHeader() ([]byte, error) {
 	funcMap := template.FuncMap{
 		"toGoType":             toGoType,
@@ -461,6 +610,7 @@ func (g *GoWsdl) genHeader() ([]byte, error) {
 		Pkg: pkgName,
 		PkgBase: g.pkg,
 		ResolvedXsdExternals: g.resolvedXsdExternals,
+		Fake: g.fake,
 	}
 
 	data := new(bytes.Buffer)
@@ -561,7 +711,7 @@ var xsd2GoTypes = map[string]string{
 	"time":          "time.Time",
 	"duration":      "time.Time",
 	"gYear":      	 "time.Time",
-	"base64Binary":  "[]byte",
+	"base64Binary":  "gowsdl.Base64Binary",
 	"hexBinary":     "[]byte",
 	"positiveInteger": "uint32",
 	"nonNegativeInteger": "uint32",
@@ -614,7 +764,9 @@ func toGoUnionType(xsdType string) string {
 	return "string"
 }
 
-// Check if the ComplexType is already been processed
+// Check if the ComplexType is already been processed. The Go names of the
+// global types and elements are unique per namespace, see
+// symbolTable.goName, so the types of different namespaces are all generated.
 func (g *GoWsdl) processComplexType(complexType string) bool {
 //	return true
 	if g.processedComplexTypes[g.currentSchema.Parent] != nil && g.processedComplexTypes[g.currentSchema.Parent][complexType] {
@@ -648,6 +800,72 @@ func (g *GoWsdl) processSimpleType(simpleType string) bool {
 	}
 }
 
+// Check if the fault type of a wsdl:fault message is already been generated
+func (g *GoWsdl) processFault(message string) bool {
+	if g.processedFaults == nil {
+		g.processedFaults = make(map[xml.Name]bool)
+	}
+	name := g.wsdlName(message)
+	if g.processedFaults[name] {
+		return false
+	}
+	g.processedFaults[name] = true
+	return true
+}
+
+// Finds the type of the detail of a wsdl:fault message. Only faults detailed
+// by a struct get a typed error, it returns "" for any other fault.
+func (g *GoWsdl) findFaultType(message string) string {
+	detailType := g.findMessageType(message)
+	if !strings.HasPrefix(detailType, "*") || detailType == "*interface{}" {
+		return ""
+	}
+	return detailType
+}
+
+// Finds the qualified name of the root element of the detail of a wsdl:fault
+// message: the element of its part or, for a part with a type, the part name
+// in any namespace. It returns nil when the message is unknown.
+func (g *GoWsdl) findFaultElement(message string) *xml.Name {
+	msg := g.findMessage(message)
+	if msg == nil || len(msg.Parts) == 0 {
+		return nil
+	}
+
+	part := msg.Parts[0]
+	if part.Type != "" {
+		return &xml.Name{Local: part.Name}
+	}
+	name := g.wsdl.resolveQName(part.Element)
+	if decl := g.findDecl(name, true); decl != nil {
+		name = decl.Name
+	}
+	return &name
+}
+
+// Filters the faults of an operation down to the ones with a typed error.
+func (g *GoWsdl) typedFaults(faults []*WsdlFault) []*WsdlFault {
+	var typed []*WsdlFault
+	for _, fault := range faults {
+		if g.findFaultType(fault.Message) != "" {
+			typed = append(typed, fault)
+		}
+	}
+	return typed
+}
+
+// Name of the error type generated for a wsdl:fault message, ie. both
+// InvalidObjectFaultMsg and InvalidObject messages become InvalidObjectFault.
+func faultTypeName(message string) string {
+	name := makePublic(replaceReservedWords(resolveQName(nil, message).Local))
+	name = strings.TrimSuffix(name, "Msg")
+	name = strings.TrimSuffix(name, "Message")
+	if !strings.HasSuffix(name, "Fault") {
+		name += "Fault"
+	}
+	return name
+}
+
 // Check if the SimpleType is already been processed
 func (g *GoWsdl) setCurrentSchema(schema *XsdSchema) string {
 	g.currentSchema = schema
@@ -663,229 +881,206 @@ func (g *GoWsdl) targetNamspace() string {
 	}
 }
 
-// Given a message, finds its type.
-//
-// I'm not very proud of this function but
-// it works for now and performance doesn't
-// seem critical at this point
-func (g *GoWsdl) findType(xmlType string) string {
-	elRefName := stripns(xmlType)
-	//	Log.Info(elRef)
+// Makes the names of the definitions being generated resolve in schema, the
+// one of a group being inlined, until the matching popSchemaScope.
+func (g *GoWsdl) pushSchemaScope(schema *XsdSchema) string {
+	g.schemaScopes = append(g.schemaScopes, schema)
+	return ""
+}
 
-	for _, msg := range g.wsdl.Messages {
-		if msg.Name != elRefName {
-			continue
-		}
+func (g *GoWsdl) popSchemaScope() string {
+	g.schemaScopes = g.schemaScopes[:len(g.schemaScopes)-1]
+	return ""
+}
 
-		// Assumes document/literal wrapped WS-I
-		if len(msg.Parts) == 0 {
-			// Message does not have parts. This could be a Port
-			// with HTTP binding or SOAP 1.2 binding, which are not currently
-			// supported.
-			Log.Warn("WSDL does seem to have HTTP or SOAP 1.2 binding which is not currently supported.")
-			continue
-		}
-		part := msg.Parts[0]
-		if part.Type != "" {
-			return stripns(part.Type)
-		}
+// Returns the schema declaring the definitions being generated, the one of
+// the innermost group being inlined, if any, or the current schema.
+func (g *GoWsdl) scopeSchema() *XsdSchema {
+	if n := len(g.schemaScopes); n > 0 {
+		return g.schemaScopes[n-1]
+	}
+	return g.currentSchema
+}
 
-		elRef := stripns(part.Element)
+// Finds the model group referenced by ref, or nil.
+func (g *GoWsdl) findGroup(ref string) *schemaDecl {
+	decl := g.symbols.group(g.scopeSchema().resolveQName(ref))
+	if decl == nil {
+		Log.Warn("Group not found", "ref", ref)
+	}
+	return decl
+}
 
-		for _, schema := range g.wsdl.Types.Schemas {
-			for _, el := range schema.Elements {
-				if strings.EqualFold(elRef, el.Name) {
-					if el.Type != "" {
-						return stripns(el.Type)
-					}
+// Finds the attribute group referenced by ref, or nil.
+func (g *GoWsdl) findAttributeGroup(ref string) *schemaDecl {
+	decl := g.symbols.attributeGroup(g.scopeSchema().resolveQName(ref))
+	if decl == nil {
+		Log.Warn("Attribute group not found", "ref", ref)
+	}
+	return decl
+}
 
-					if(!g.importsNeeded["basetypes"]){
-						g.importsNeeded["basetypes"] = true
-					}
+// Returns the choices of the content model of the complex type being
+// generated.
+func (g *GoWsdl) contentChoices(content *XsdCompositor) *contentChoices {
+	return g.symbols.contentChoices(g, content)
+}
 
-					fullname := "basetypes."+makePublic(replaceReservedWords(el.Name))
-					return fullname
-					//					return el.Name
-				}
-			}
-		}
+// Returns the base type of a restriction of the schema being generated and
+// the types it derives from.
+func (g *GoWsdl) ancestors(base string) []*schemaDecl {
+	return g.symbols.ancestors(g.scopeSchema(), base)
+}
 
-		//Inserted here in the Messages for in order to avoid reference not corrected (Amadeus for example!)
-		//TODO Check if it works for all the wsdl -> if there are some as the OTA xsd could be a problem... to solve move this for out of the messages for
-		for key, schema := range g.resolvedXsdExternals {
-			fullname := ""
-			for _, el := range schema.SimpleType {
-				if strings.EqualFold(elRef, el.Name) {
-					if(key == g.currentSchema.Parent){
-						fullname = "*" +makePublic(replaceReservedWords(el.Name))
-						//						fullname = "*" +makePublic(replaceReservedWords(key))+"."+makePublic(replaceReservedWords(el.Name))
-					}else{
-						pkg := makePublic(replaceReservedWords(key))
-						if(!g.importsNeeded[pkg]){
-							g.importsNeeded[pkg] = true
-						}
-						fullname = "*" +pkg+"."+makePublic(replaceReservedWords(el.Name))
-					}
-					return fullname
-				}
-			}
-			for _, el := range schema.ComplexTypes {
-				if strings.EqualFold(elRef, el.Name) {
-					if(key == g.currentSchema.Parent){
-						fullname = "*" +makePublic(replaceReservedWords(el.Name))
-						//						fullname = "*" +makePublic(replaceReservedWords(key))+"."+makePublic(replaceReservedWords(el.Name))
-					}else{
-						pkg := makePublic(replaceReservedWords(key))
-						if(!g.importsNeeded[pkg]){
-							g.importsNeeded[pkg] = true
-						}
-						fullname = "*" +pkg+"."+makePublic(replaceReservedWords(el.Name))
-					}
-					return fullname
-				}			}
-			for _, el := range schema.AttributeGoups {
-				if strings.EqualFold(elRef, el.Name) {
-					if(key == g.currentSchema.Parent){
-						fullname = "*" +makePublic(replaceReservedWords(el.Name))
-						//						fullname = "*" +makePublic(replaceReservedWords(key))+"."+makePublic(replaceReservedWords(el.Name))
-					}else{
-						pkg := makePublic(replaceReservedWords(key))
-						if(!g.importsNeeded[pkg]){
-							g.importsNeeded[pkg] = true
-						}
-						fullname = "*" +pkg+"."+makePublic(replaceReservedWords(el.Name))
-					}
-					return fullname
-				}
-			}
+// Returns the Go name of the global type local of the schema being
+// generated.
+func (g *GoWsdl) typeName(local string) string {
+	return g.symbols.goTypeName(g.scopeSchema(), local, false)
+}
 
-			for _, el := range schema.Elements {
-				if strings.EqualFold(elRef, el.Name) {
-					elName := ""
-					if el.Type != "" {
-						elName = stripns(el.Type)
-					}else{
-						elName = makePublic(replaceReservedWords(el.Name))
-					}
-
-					if(key == g.currentSchema.Parent){
-						fullname = "*" +makePublic(replaceReservedWords(elName))
-						//						fullname = "*" +makePublic(replaceReservedWords(key))+"."+elName
-					}else{
-						pkg := makePublic(replaceReservedWords(key))
-						if(!g.importsNeeded[pkg]){
-							g.importsNeeded[pkg] = true
-						}
-						fullname = "*" +pkg+"."+makePublic(replaceReservedWords(elName))
-					}
-					//					Log.Info(fullname)
-					return fullname
-				}
-			}
-		}
-	}
+// Reports whether the global type local of the schema being generated is
+// also generated for the global element of the same name.
+func (g *GoWsdl) typeIsElement(local string) bool {
+	return g.symbols.typeIsElement(g.scopeSchema(), local)
+}
+
+// Returns the Go name of the global element local of the schema being
+// generated.
+func (g *GoWsdl) elementTypeName(local string) string {
+	return g.symbols.goTypeName(g.scopeSchema(), local, true)
+}
 
+// Reports whether complexType, a global complex type of the schema being
+// generated, is abstract or has derived types.
+func (g *GoWsdl) isPolymorphic(complexType *XsdComplexType) bool {
+	return g.symbols.polymorphic(g.symbols.complexType(g.scopeSchema(), complexType))
+}
 
-	if(isBaseType(xmlType)){
-		return toGoType(replaceReservedWords(xmlType))
-	}else{
-		return toGoType(replaceReservedWords(strings.Title(xmlType)))
+// Returns the holder of the values of xmlType, a type of the schema being
+// generated, when it is polymorphic, or "".
+func (g *GoWsdl) polymorphicType(xmlType string) string {
+	decl := g.symbols.lookup(g.scopeSchema().resolveQName(xmlType), false)
+	if !g.symbols.polymorphic(decl) {
+		return ""
+	}
+	if decl.Pkg == g.currentSchema.Parent {
+		return "*Any" + decl.GoName
 	}
+	g.importsNeeded[decl.Pkg] = true
+	return decl.anyType()
 }
 
-func (g *GoWsdl) findMessageType(xmlType string) string {
-	elRefName := stripns(xmlType)
-	//	Log.Info(elRef)
+// Returns the complex types of the schema being generated registered for
+// xsi:type.
+func (g *GoWsdl) xsiTypes() []*schemaDecl {
+	return g.symbols.xsiTypes(g.scopeSchema())
+}
 
-	for _, msg := range g.wsdl.Messages {
-		if msg.Name != elRefName {
-			continue
-		}
+// Reports whether base, the base type of a restriction of the schema being
+// generated, is soapenc:Array.
+func (g *GoWsdl) isSoapArrayBase(base string) bool {
+	return isSoapArray(g.scopeSchema().resolveQName(base))
+}
 
-		// Assumes document/literal wrapped WS-I
-		if len(msg.Parts) == 0 {
-			// Message does not have parts. This could be a Port
-			// with HTTP binding or SOAP 1.2 binding, which are not currently
-			// supported.
-			Log.Warn("WSDL does seem to have HTTP or SOAP 1.2 binding which is not currently supported.")
-			continue
-		}
-		part := msg.Parts[0]
-		if part.Type != "" {
-			return stripns(part.Type)
+// Returns the struct tag name of a local element of the schema being
+// generated, see XsdSchema.elementName.
+func (g *GoWsdl) elementName(name, form string) string {
+	return g.scopeSchema().elementName(name, form)
+}
+
+// Returns the struct tag name of a local attribute of the schema being
+// generated, see XsdSchema.attributeName.
+func (g *GoWsdl) attributeName(name, form string) string {
+	return g.scopeSchema().attributeName(name, form)
+}
+
+// Finds the Go type of a type, element or attribute group reference of the
+// schema being generated, qualified with its package when it is generated in
+// another one.
+func (g *GoWsdl) findType(xmlType string) string {
+	name := g.resolveQName(xmlType)
+	if name.Space == XsdNamespace {
+		return toGoType(name.Local)
+	}
+
+	decl := g.findDecl(name, false)
+	if decl == nil {
+		if isBaseType(xmlType) {
+			return toGoType(replaceReservedWords(xmlType))
 		}
+		return toGoType(replaceReservedWords(strings.Title(xmlType)))
+	}
 
-		elRef := stripns(part.Element)
+	if g.currentSchema != nil && decl.Pkg == g.currentSchema.Parent {
+		return "*" + decl.GoName
+	}
+	g.importsNeeded[decl.Pkg] = true
+	return decl.goType()
+}
 
-		for _, schema := range g.wsdl.Types.Schemas {
-			for _, el := range schema.Elements {
-				if strings.EqualFold(elRef, el.Name) {
-					if el.Type != "" {
-						return stripns(el.Type)
-					}
+// Returns the namespace and local name of an element reference of the
+// schema being generated, as used in the xml struct tag of its field, or ""
+// if no schema declares it.
+func (g *GoWsdl) qualifiedName(ref string) string {
+	decl := g.findDecl(g.resolveQName(ref), true)
+	if decl == nil || decl.Name.Space == "" {
+		return ""
+	}
+	return decl.Name.Space + " " + decl.Name.Local
+}
 
-					if(!g.importsNeeded["basetypes"]){
-						g.importsNeeded["basetypes"] = true
-					}
+// Resolves a QName of the schema being generated, or of the definitions
+// outside of the types.
+func (g *GoWsdl) resolveQName(qname string) xml.Name {
+	if schema := g.scopeSchema(); schema != nil {
+		return schema.resolveQName(qname)
+	}
+	return g.wsdl.resolveQName(qname)
+}
 
-					fullname := "*basetypes."+makePublic(replaceReservedWords(el.Name))
-					return fullname
-					//					return el.Name
-				}
-			}
-		}
+// Finds the global declaration named name among the schemas of the WSDL and
+// the external ones, or nil, see symbolTable.lookup.
+func (g *GoWsdl) findDecl(name xml.Name, element bool) *schemaDecl {
+	return g.symbols.lookup(name, element)
+}
 
-		for key, schema := range g.resolvedXsdExternals {
-			fullname := ""
-			for _, el := range schema.SimpleType {
-				if strings.EqualFold(elRef, el.Name) {
-					pkg := makePublic(replaceReservedWords(key))
-					if(!g.importsNeeded[pkg]){
-						g.importsNeeded[pkg] = true
-					}
-					fullname = "*" +pkg+"."+makePublic(replaceReservedWords(el.Name))
-
-					return fullname
-				}
-			}
-			for _, el := range schema.ComplexTypes {
-				if strings.EqualFold(elRef, el.Name) {
-					pkg := makePublic(replaceReservedWords(key))
-					if(!g.importsNeeded[pkg]){
-						g.importsNeeded[pkg] = true
-					}
-					fullname = "*" +pkg+"."+makePublic(replaceReservedWords(el.Name))
-					return fullname
-				}			}
-			for _, el := range schema.AttributeGoups {
-				if strings.EqualFold(elRef, el.Name) {
-					pkg := makePublic(replaceReservedWords(key))
-					if(!g.importsNeeded[pkg]){
-						g.importsNeeded[pkg] = true
-					}
-					fullname = "*" +pkg+"."+makePublic(replaceReservedWords(el.Name))
-					return fullname
-				}
-			}
+// Resolves a reference to a message, port type or binding of the WSDL, which
+// are all defined in its target namespace. Names with an undeclared prefix
+// are taken to be in the target namespace.
+func (g *GoWsdl) wsdlName(qname string) xml.Name {
+	name := g.wsdl.resolveQName(qname)
+	if name.Space == "" {
+		name.Space = g.wsdl.TargetNamespace
+	}
+	return name
+}
 
-			for _, el := range schema.Elements {
-				if strings.EqualFold(elRef, el.Name) {
-					elName := ""
-					if el.Type != "" {
-						elName = stripns(el.Type)
-					}else{
-						elName = makePublic(replaceReservedWords(el.Name))
-					}
-
-					pkg := makePublic(replaceReservedWords(key))
-					if(!g.importsNeeded[pkg]){
-						g.importsNeeded[pkg] = true
-					}
-					fullname = "*" +pkg+"."+makePublic(replaceReservedWords(elName))
-
-					return fullname
-				}
-			}
+// Whether qname references the message, port type or binding named local.
+func (g *GoWsdl) isWsdlName(qname, local string) bool {
+	return g.wsdlName(qname) == xml.Name{Space: g.wsdl.TargetNamespace, Local: local}
+}
+
+// Returns the message referenced by qname, or nil.
+func (g *GoWsdl) findMessage(qname string) *WsdlMessage {
+	return g.symbols.message(g.wsdlName(qname))
+}
+
+// Given a message, finds the type of its body for the operations template.
+//
+// Unlike findType it does not record imports, the operations header already
+// imports every package and the operations are generated concurrently with
+// the types, which own importsNeeded.
+func (g *GoWsdl) findMessageType(xmlType string) string {
+	if msg := g.findMessage(xmlType); msg != nil {
+		// Assumes document/literal wrapped WS-I
+		if len(msg.Parts) == 0 {
+			// Message does not have parts. This could be a Port
+			// with HTTP binding, which is not currently supported.
+			Log.Warn("WSDL does seem to have HTTP binding which is not currently supported.")
+		} else if part := msg.Parts[0]; part.Type != "" {
+			return stripns(part.Type)
+		} else if decl := g.findDecl(g.wsdl.resolveQName(part.Element), true); decl != nil {
+			return decl.goType()
 		}
 	}
 
@@ -958,35 +1153,552 @@ func (g *GoWsdl) findMessageType(xmlType string) string {
 //	return ""
 //}
 
-// TODO(c4milo): Add support for namespaces instead of striping them out
-// TODO(c4milo): improve runtime complexity if performance turns out to be an issue.
-func (g *GoWsdl) findSoapAction(operation, portType string) string {
+// Finds the SOAP binding of a port type. SOAP 1.1 bindings are preferred over
+// SOAP 1.2 ones when the WSDL provides both for the same port type.
+func (g *GoWsdl) findBinding(portType string) *WsdlBinding {
+	var soap12Binding *WsdlBinding
 	for _, binding := range g.wsdl.Binding {
-		if stripns(binding.Type) != portType {
+		if !g.isWsdlName(binding.Type, portType) {
 			continue
 		}
 
-		for _, soapOp := range binding.Operations {
-			if soapOp.Name == operation {
+		if binding.SoapBinding.Transport != "" {
+			return binding
+		}
+		if soap12Binding == nil && binding.Soap12Binding.Transport != "" {
+			soap12Binding = binding
+		}
+	}
+	return soap12Binding
+}
+
+// Whether the port type has a SOAP 1.1 or 1.2 binding, port types only bound
+// to HTTP GET/POST are not generated.
+func (g *GoWsdl) hasSoapBinding(portType string) bool {
+	return g.findBinding(portType) != nil
+}
+
+// Whether the port type is only reachable through a SOAP 1.2 binding.
+func (g *GoWsdl) isSoap12(portType string) bool {
+	binding := g.findBinding(portType)
+	return binding != nil && binding.SoapBinding.Transport == ""
+}
+
+func (g *GoWsdl) findSoapAction(operation, portType string) string {
+	binding := g.findBinding(portType)
+	if binding == nil {
+		return ""
+	}
+
+	for _, soapOp := range binding.Operations {
+		if soapOp.Name == operation {
+			if soapOp.SoapOperation.SoapAction != "" {
 				return soapOp.SoapOperation.SoapAction
 			}
+			return soapOp.Soap12Operation.SoapAction
 		}
 	}
 	return ""
 }
 
+// Whether the binding of the port type uses WS-Addressing, either through
+// wsaw:UsingAddressing, a wsam:Addressing policy assertion or explicit actions
+// on its operations.
+func (g *GoWsdl) usesAddressing(portType string) bool {
+	binding := g.findBinding(portType)
+	if binding == nil {
+		return false
+	}
+	if binding.UsingAddressing != nil {
+		return true
+	}
+	visited := make(map[string]bool)
+	for _, policy := range binding.Policies {
+		if g.policyUsesAddressing(policy, visited) {
+			return true
+		}
+	}
+	for _, reference := range binding.PolicyReferences {
+		if g.referenceUsesAddressing(reference, visited) {
+			return true
+		}
+	}
+
+	for _, pt := range g.wsdl.PortTypes {
+		if !g.isWsdlName(binding.Type, pt.Name) {
+			continue
+		}
+		for _, op := range pt.Operations {
+			if op.Input.WsawAction != "" || op.Input.WsamAction != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (g *GoWsdl) policyUsesAddressing(policy *WsdlPolicy, visited map[string]bool) bool {
+	if policy.Addressing {
+		return true
+	}
+	for _, reference := range policy.PolicyReferences {
+		if g.referenceUsesAddressing(reference, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// Follows a reference to a policy of the definitions, by its id for "#id"
+// references and by its name otherwise. Each policy is only looked at once.
+func (g *GoWsdl) referenceUsesAddressing(reference *WsdlPolicyReference, visited map[string]bool) bool {
+	id := strings.TrimPrefix(reference.URI, "#")
+	if visited[id] {
+		return false
+	}
+	visited[id] = true
+
+	for _, policy := range g.wsdl.Policies {
+		if policy.Id == id {
+			return g.policyUsesAddressing(policy, visited)
+		}
+	}
+	return false
+}
+
+// Returns the WS-Addressing action of the input of an operation, or "" if
+// the port type doesn't use WS-Addressing. Explicit wsaw:Action or
+// wsam:Action attributes come first, then the SOAP action of the binding
+// and finally the default action pattern of WS-Addressing Metadata:
+// [target namespace]/[port type]/[input name].
+func (g *GoWsdl) findAddressingAction(operation *WsdlOperation, portType string) string {
+	if !g.usesAddressing(portType) {
+		return ""
+	}
+
+	if operation.Input.WsawAction != "" {
+		return operation.Input.WsawAction
+	}
+	if operation.Input.WsamAction != "" {
+		return operation.Input.WsamAction
+	}
+	if soapAction := g.findSoapAction(operation.Name, portType); soapAction != "" {
+		return soapAction
+	}
+
+	input := operation.Input.Name
+	if input == "" {
+		input = operation.Name + "Request"
+	}
+
+	namespace, delimiter := g.wsdl.TargetNamespace, "/"
+	if strings.HasPrefix(namespace, "urn:") {
+		delimiter = ":"
+	}
+	return strings.TrimSuffix(namespace, delimiter) + delimiter + portType + delimiter + input
+}
+
+// Request and response of an operation whose messages don't map to a single
+// body element, nil for the ones that do.
+type operationParts struct {
+	Request  *partsMessage
+	Response *partsMessage
+}
+
+// Message sent or received as a struct holding its body parts. The parts of
+// RPC style messages are wrapped in the Element wrapper element,
+// EncodingStyle being "" for literal messages.
+type partsMessage struct {
+	Message       string
+	RPC           bool
+	Namespace     string
+	Element       string
+	EncodingStyle string
+	Parts         []*messagePart
+}
+
+// Typed request and response headers of an operation, nil when the operation
+// has no header. The Faults of the response header are the parts of the
+// soap:headerfault elements, sent along with faults.
+type operationHeaders struct {
+	Request  *headerMessage
+	Response *headerMessage
+}
+
+type headerMessage struct {
+	Parts  []*messagePart
+	Faults []*messagePart
+}
+
+func (h *headerMessage) AllParts() []*messagePart {
+	return append(append([]*messagePart{}, h.Parts...), h.Faults...)
+}
+
+// Message part, sent as the Element element unless it is an accessor of an
+// RPC wrapper element. Type and ItemType are the xsi:type of the part and of
+// the items of SOAP encoded arrays.
+type messagePart struct {
+	Name     string
+	Field    string
+	GoType   string
+	Element  xml.Name
+	Type     xml.Name
+	ItemType xml.Name
+}
+
+// Returns the binding operation of an operation of a port type.
+func (g *GoWsdl) findBindingOperation(operation, portType string) (*WsdlBinding, *WsdlOperation) {
+	binding := g.findBinding(portType)
+	if binding == nil {
+		return nil, nil
+	}
+
+	for _, op := range binding.Operations {
+		if op.Name == operation {
+			return binding, op
+		}
+	}
+	return binding, nil
+}
+
+// Returns the request and response structs of an operation. They are only
+// generated for RPC style operations, the style of the binding operation
+// overriding the one of the binding, and for document style messages whose
+// body isn't their first part alone, ie. with several body parts or with
+// parts bound to soap:header; document/literal wrapped messages are sent as
+// their single element.
+func (g *GoWsdl) operationParts(operation *WsdlOperation, portType string) *operationParts {
+	binding, bindingOp := g.findBindingOperation(operation.Name, portType)
+	if binding == nil || bindingOp == nil {
+		return &operationParts{}
+	}
+
+	soap12 := binding.SoapBinding.Transport == ""
+	style := bindingOp.SoapOperation.Style
+	if soap12 {
+		style = bindingOp.Soap12Operation.Style
+	}
+	if style == "" {
+		style = binding.SoapBinding.Style
+		if soap12 {
+			style = binding.Soap12Binding.Style
+		}
+	}
+	rpc := style == "rpc"
+
+	inputBody, inputHeaders := bindingOp.Input.SoapBody, bindingOp.Input.SoapHeader
+	outputBody, outputHeaders := bindingOp.Output.SoapBody, bindingOp.Output.SoapHeader
+	if soap12 {
+		inputBody, inputHeaders = bindingOp.Input.Soap12Body, bindingOp.Input.Soap12Header
+		outputBody, outputHeaders = bindingOp.Output.Soap12Body, bindingOp.Output.Soap12Header
+	}
+
+	return &operationParts{
+		Request:  g.partsMessage(operation.Input.Message, operation.Name, rpc, inputBody, inputHeaders, soap12),
+		Response: g.partsMessage(operation.Output.Message, operation.Name+"Response", rpc, outputBody, outputHeaders, soap12),
+	}
+}
+
+func (g *GoWsdl) partsMessage(message, element string, rpc bool, body WsdlSoapBody, headers []*WsdlSoapHeader, soap12 bool) *partsMessage {
+	m := g.findMessage(message)
+	if m == nil {
+		return nil
+	}
+
+	msg := &partsMessage{Message: m.Name, RPC: rpc, Namespace: body.Namespace, Element: element}
+	if msg.Namespace == "" {
+		msg.Namespace = g.wsdl.TargetNamespace
+	}
+	if body.Use == "encoded" {
+		if fields := strings.Fields(body.EncodingStyle); len(fields) > 0 {
+			msg.EncodingStyle = fields[0]
+		} else if soap12 {
+			msg.EncodingStyle = Soap12EncodingNamespace
+		} else {
+			msg.EncodingStyle = SoapEncodingNamespace
+		}
+	}
+
+	// soap:body parts="..." lists the body parts, the parts bound to
+	// soap:header are sent in the typed header of the operation.
+	bodyParts := strings.Fields(body.Parts)
+	var plainParts []*WsdlPart
+	for _, part := range m.Parts {
+		inHeader := false
+		for _, header := range headers {
+			if g.isWsdlName(header.Message, m.Name) && header.Part == part.Name {
+				inHeader = true
+			}
+		}
+
+		if !inHeader && (body.Parts == "" || containsString(bodyParts, part.Name)) {
+			msg.Parts = append(msg.Parts, g.messagePart(part, rpc))
+			plainParts = append(plainParts, part)
+		}
+	}
+
+	if !rpc && (len(m.Parts) == 0 || len(plainParts) == 1 && plainParts[0] == m.Parts[0]) {
+		return nil
+	}
+	return msg
+}
+
+// Returns the typed headers of an operation, from the soap:header elements
+// of its binding operation.
+func (g *GoWsdl) operationHeaders(operation *WsdlOperation, portType string) *operationHeaders {
+	binding, bindingOp := g.findBindingOperation(operation.Name, portType)
+	if binding == nil || bindingOp == nil {
+		return &operationHeaders{}
+	}
+
+	inputHeaders, outputHeaders := bindingOp.Input.SoapHeader, bindingOp.Output.SoapHeader
+	if binding.SoapBinding.Transport == "" {
+		inputHeaders, outputHeaders = bindingOp.Input.Soap12Header, bindingOp.Output.Soap12Header
+	}
+
+	request, response := &headerMessage{}, &headerMessage{}
+	fields := map[string]bool{}
+	for _, header := range inputHeaders {
+		request.Parts = g.appendHeaderPart(request.Parts, header.Message, header.Part, fields)
+	}
+
+	fields = map[string]bool{}
+	for _, header := range outputHeaders {
+		response.Parts = g.appendHeaderPart(response.Parts, header.Message, header.Part, fields)
+	}
+	for _, headers := range [][]*WsdlSoapHeader{inputHeaders, outputHeaders} {
+		for _, header := range headers {
+			for _, fault := range header.HeadersFault {
+				response.Faults = g.appendHeaderPart(response.Faults, fault.Message, fault.Part, fields)
+			}
+		}
+	}
+
+	headers := &operationHeaders{}
+	if len(request.Parts) > 0 {
+		headers.Request = request
+	}
+	if len(response.Parts) > 0 || len(response.Faults) > 0 {
+		headers.Response = response
+	}
+	return headers
+}
+
+// Appends the part of a message bound to a header to parts. Parts whose field
+// is already used, by another header with the same part name, are prefixed
+// with their message name.
+func (g *GoWsdl) appendHeaderPart(parts []*messagePart, message, partName string, fields map[string]bool) []*messagePart {
+	if m := g.findMessage(message); m != nil {
+		for _, part := range m.Parts {
+			if part.Name != partName {
+				continue
+			}
+
+			p := g.messagePart(part, false)
+			if fields[p.Field] {
+				p.Field = makePublic(replaceReservedWords(m.Name)) + p.Field
+			}
+			fields[p.Field] = true
+			return append(parts, p)
+		}
+	}
+
+	Log.Warn("soap:header part not found", "message", message, "part", partName)
+	return parts
+}
+
+// Returns a message part, accessor is set for the parts of RPC wrapper
+// elements.
+func (g *GoWsdl) messagePart(part *WsdlPart, accessor bool) *messagePart {
+	p := &messagePart{
+		Name:    part.Name,
+		Field:   makePublic(replaceReservedWords(part.Name)),
+		Element: xml.Name{Local: part.Name},
+	}
+
+	if part.Type == "" {
+		p.Element = g.wsdl.resolveQName(part.Element)
+		p.GoType = "*basetypes." + makePublic(replaceReservedWords(p.Element.Local))
+		if decl := g.findDecl(p.Element, true); decl != nil {
+			p.Element = decl.Name
+			p.GoType = decl.goType()
+			if decl.Type.Local != "" {
+				p.GoType = g.partGoType(decl.Type)
+			}
+		} else if p.Element.Space == "" {
+			p.Element.Space = g.wsdl.TargetNamespace
+		}
+		if accessor {
+			p.Element = xml.Name{Local: part.Name}
+		}
+		return p
+	}
+
+	partType := g.wsdl.resolveQName(part.Type)
+	if itemType := g.soapArrayItemType(partType); itemType.Local != "" {
+		p.GoType = "[]" + g.partGoType(itemType)
+		p.Type = xml.Name{Space: SoapEncodingNamespace, Local: "Array"}
+		p.ItemType = g.xsdTypeName(itemType)
+		return p
+	}
+
+	p.GoType = g.partGoType(partType)
+	p.Type = g.xsdTypeName(partType)
+	return p
+}
+
+// Go types of the generated method of an operation along with the SOAP
+// action and the body element its requests are dispatched on. The header
+// types are "" for operations without typed headers.
+type operationSignature struct {
+	Method         string
+	Request        string
+	Response       string
+	RequestHeader  string
+	ResponseHeader string
+	Action         string
+	Element        xml.Name
+}
+
+func (g *GoWsdl) operationSignature(operation *WsdlOperation, portType string) *operationSignature {
+	method := replaceReservedWords(makePublic(operation.Name))
+	sig := &operationSignature{
+		Method:   method,
+		Request:  g.findMessageType(operation.Input.Message),
+		Response: g.findMessageType(operation.Output.Message),
+		Action:   g.findSoapAction(operation.Name, portType),
+	}
+	if action := g.findAddressingAction(operation, portType); action != "" {
+		sig.Action = action
+	}
+
+	parts := g.operationParts(operation, portType)
+	if parts.Request != nil {
+		sig.Request = "*" + method + "Request"
+		if parts.Request.RPC {
+			sig.Element = xml.Name{Space: parts.Request.Namespace, Local: parts.Request.Element}
+		} else if len(parts.Request.Parts) > 0 {
+			sig.Element = parts.Request.Parts[0].Element
+		}
+	} else {
+		if m := g.findMessage(operation.Input.Message); m != nil && len(m.Parts) > 0 {
+			sig.Element = g.messagePart(m.Parts[0], false).Element
+		}
+	}
+	if parts.Response != nil {
+		sig.Response = "*" + method + "Response"
+	}
+
+	headers := g.operationHeaders(operation, portType)
+	if headers.Request != nil {
+		sig.RequestHeader = "*" + method + "RequestHeader"
+	}
+	if headers.Response != nil {
+		sig.ResponseHeader = "*" + method + "ResponseHeader"
+	}
+	return sig
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *GoWsdl) partGoType(name xml.Name) string {
+	if isXsdType(name) {
+		return toGoType(name.Local)
+	}
+	if decl := g.findDecl(name, false); decl != nil {
+		return decl.goType()
+	}
+	return "*basetypes." + makePublic(replaceReservedWords(name.Local))
+}
+
+// Returns the qualified name of a schema type, the built-in types of an
+// undeclared prefix being in the XML Schema namespace and others in the
+// namespace of their schema.
+func (g *GoWsdl) xsdTypeName(name xml.Name) xml.Name {
+	if isXsdType(name) {
+		return xml.Name{Space: XsdNamespace, Local: name.Local}
+	}
+	if decl := g.findDecl(name, false); decl != nil {
+		return decl.Name
+	}
+	if name.Space == "" {
+		name.Space = g.wsdl.TargetNamespace
+	}
+	return name
+}
+
+// Whether name is a built-in type of XML Schema, tolerating undeclared
+// prefixes.
+func isXsdType(name xml.Name) bool {
+	return name.Space == XsdNamespace || name.Space == "" && isBaseType(name.Local)
+}
+
+// Returns the item type of a SOAP encoded array type, a restriction of
+// soapenc:Array, or an empty name if typeName isn't one.
+func (g *GoWsdl) soapArrayItemType(typeName xml.Name) xml.Name {
+	anyType := xml.Name{Space: XsdNamespace, Local: "anyType"}
+	if isSoapArray(typeName) {
+		return anyType
+	}
+
+	decl := g.findDecl(typeName, false)
+	if decl == nil || decl.ComplexType == nil {
+		return xml.Name{}
+	}
+	schema := decl.Schema
+	restriction := decl.ComplexType.ComplexContent.Restriction
+	if !isSoapArray(schema.resolveQName(restriction.Base)) {
+		return xml.Name{}
+	}
+
+	// wsdl:arrayType="xsd:string[]"
+	for _, attr := range restriction.Attributes {
+		if attr.ArrayType != "" {
+			return schema.resolveQName(attr.ArrayType[:strings.Index(attr.ArrayType+"[", "[")])
+		}
+	}
+	for _, el := range restriction.Content().elements() {
+		if el.Type != "" {
+			return schema.resolveQName(el.Type)
+		}
+	}
+	return anyType
+}
+
+// Whether name is soapenc:Array, tolerating undeclared prefixes.
+func isSoapArray(name xml.Name) bool {
+	if name.Local != "Array" {
+		return false
+	}
+	return name.Space == SoapEncodingNamespace || name.Space == Soap12EncodingNamespace || name.Space == ""
+}
+
+// Returns the address of the port bound to the binding the client of the
+// port type is generated for.
 func (g *GoWsdl) findServiceAddress(name string) string {
+	binding := g.findBinding(name)
 	for _, service := range g.wsdl.Service {
 		for _, port := range service.Ports {
-			if port.Name == name {
-				return port.SoapAddress.Location
+			if binding != nil && g.isWsdlName(port.Binding, binding.Name) {
+				if port.SoapAddress.Location != "" {
+					return port.SoapAddress.Location
+				}
+				return port.Soap12Address.Location
 			}
 		}
 	}
 	return ""
 }
 
-// TODO(c4milo): Add namespace support instead of stripping it
+// Returns the local name of a QName, for the names of the generated types and
+// fields.
 func stripns(xsdType string) string {
 	r := strings.Split(xsdType, ":")
 	type_ := r[0]
@@ -1050,6 +1762,11 @@ func isArrayElement(maxOccur string) bool {
 	return i > 1
 }
 
+// Returns a list of one element, for templates ranging over lists.
+func elementList(el *XsdElement) []*XsdElement {
+	return []*XsdElement{el}
+}
+
 //dictionary map to pass multiple params to a template
 func dictValues(values ...interface{}) (map[string]interface{}, error) {
 	if len(values)%2 != 0 {
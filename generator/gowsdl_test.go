@@ -0,0 +1,721 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"bytes"
+	"go/format"
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+// The packages under testdata are generated from fixtures and used by the
+// tests of the generated code, they have to be regenerated when the templates
+// change, ie. with "gowsdl -p github.com/hooklift/gowsdl/generator/testdata/faults
+// -o faults.go ../fixtures/faults.wsdl" from the testdata directory, adding
+// --server for shipping.
+func TestGenTestdata(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []GeneratorOption
+	}{
+		{"faults", nil},
+		{"shipping", []GeneratorOption{GenerateServer()}},
+	}
+
+	for _, test := range tests {
+		name := test.name
+		g, err := NewGoWsdl("fixtures/"+name+".wsdl", "github.com/hooklift/gowsdl/generator/testdata/"+name, false, test.opts...)
+		if err != nil {
+			t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+		}
+
+		gocode, gotypes, err := g.Start()
+		if err != nil {
+			t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+		}
+
+		// Assembled like the files written by the gowsdl command.
+		files := map[string][]byte{
+			name + ".go": bytes.Join([][]byte{gocode["header"], gocode["operations"], gocode["server"], gocode["fake"]}, nil),
+		}
+		for key, gotype := range gotypes {
+			files[path.Join(key, key+".go")] = gotype
+		}
+
+		for file, code := range files {
+			want, err := format.Source(code)
+			if err != nil {
+				t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+			}
+			got, err := ioutil.ReadFile(path.Join("testdata", name, file))
+			if err != nil {
+				t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("testdata/%s/%s is out of date, regenerate it from fixtures/%s.wsdl", name, file, name)
+			}
+		}
+	}
+}
+
+func TestFaultTypeName(t *testing.T) {
+	tests := map[string]string{
+		"vbox:InvalidObjectFaultMsg": "InvalidObjectFault",
+		"tns:invalidCredentials":     "InvalidCredentialsFault",
+		"RuntimeFaultMessage":        "RuntimeFault",
+	}
+
+	for message, want := range tests {
+		got := faultTypeName(message)
+		if got != want {
+			t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", got, want)
+		}
+	}
+}
+
+func TestGenOperationsTypedFaults(t *testing.T) {
+	g, err := NewGoWsdl("fixtures/vboxweb.wsdl", "myservice", false)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	gocode, _, err := g.Start()
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	operations := gocode["operations"]
+	for _, want := range []string{
+		"type InvalidObjectFault struct",
+		"FaultDetail *basetypes.InvalidObjectFault",
+		"if typedFault := newRuntimeFault(fault); typedFault != nil {",
+		// Both faults of the operation are told apart by their detail element.
+		`if name := fault.DetailName(); name.Local != "InvalidObjectFault" || name.Space != "http://www.virtualbox.org/" {`,
+		`if name := fault.DetailName(); name.Local != "RuntimeFault" || name.Space != "http://www.virtualbox.org/" {`,
+	} {
+		if !bytes.Contains(operations, []byte(want)) {
+			t.Errorf("generated operations do not contain %q", want)
+		}
+	}
+
+	if count := bytes.Count(operations, []byte("type RuntimeFault struct")); count != 1 {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", count, 1)
+	}
+}
+
+func TestGenOperationsAddressing(t *testing.T) {
+	g, err := NewGoWsdl("fixtures/addressing.wsdl", "myservice", false)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	gocode, _, err := g.Start()
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	operations := gocode["operations"]
+	for _, want := range []string{
+		"gowsdl.WithWSAddressing()",
+		// explicit wsaw:Action
+		`service.client.CallContext(ctx, "http://example.com/greeter/Greeter/Greet",`,
+		// default action pattern
+		`service.client.CallContext(ctx, "http://example.com/greeter/Greeter/WaveRequest",`,
+	} {
+		if !bytes.Contains(operations, []byte(want)) {
+			t.Errorf("generated operations do not contain %q", want)
+		}
+	}
+}
+
+func TestGenOperationsAddressingPolicy(t *testing.T) {
+	g, err := NewGoWsdl("fixtures/addressing-policy.wsdl", "myservice", false)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	gocode, _, err := g.Start()
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	operations := gocode["operations"]
+	for _, want := range []string{
+		"gowsdl.WithWSAddressing()",
+		`service.client.CallContext(ctx, "http://example.com/tracker/Track",`,
+	} {
+		if !bytes.Contains(operations, []byte(want)) {
+			t.Errorf("generated operations do not contain %q", want)
+		}
+	}
+}
+
+func TestGenOperationsServiceAddress(t *testing.T) {
+	g, err := NewGoWsdl("fixtures/quotes.wsdl", "myservice", false)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	gocode, _, err := g.Start()
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	// The port named after the port type is bound to SOAP 1.2, the client
+	// uses the SOAP 1.1 binding.
+	operations := gocode["operations"]
+	for _, want := range []string{
+		`url = "http://localhost:8080/quotes/soap"`,
+		"gowsdl.NewSoapClient(url, tls, opts...)",
+	} {
+		if !bytes.Contains(operations, []byte(want)) {
+			t.Errorf("generated operations do not contain %q", want)
+		}
+	}
+}
+
+func TestToGoType(t *testing.T) {
+	tests := map[string]string{
+		"xs:string":        "string",
+		"xsd:base64Binary": "gowsdl.Base64Binary",
+		"tns:Document":     "*Document",
+	}
+
+	for xsdType, want := range tests {
+		got := toGoType(xsdType)
+		if got != want {
+			t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", got, want)
+		}
+	}
+}
+
+func TestGenOperationsRPC(t *testing.T) {
+	g, err := NewGoWsdl("fixtures/rpc.wsdl", "myservice", false)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	gocode, _, err := g.Start()
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	operations := gocode["operations"]
+	for _, want := range []string{
+		// rpc/encoded
+		`gowsdl.EncodeRPC(e, xml.Name{Space: "urn:inventory", Local: "getItem"}, "http://schemas.xmlsoap.org/soap/encoding/",`,
+		`{Name: "warehouse", Type: xml.Name{Space: "http://www.w3.org/2001/XMLSchema", Local: "int"}, Value: r.Warehouse}`,
+		"Skus []string",
+		`"skus": &r.Skus,`,
+		"func (r *GetItemResponse) SoapEncodingStyle() string {",
+		// rpc/literal
+		`gowsdl.EncodeRPC(e, xml.Name{Space: "urn:inventory", Local: "count"}, "",`,
+		"request *CountRequest, header *gowsdl.SoapHeader",
+		"response := &CountResponse{}",
+	} {
+		if !bytes.Contains(operations, []byte(want)) {
+			t.Errorf("generated operations do not contain %q", want)
+		}
+	}
+	// Only the encoded messages resolve multi-reference values.
+	if bytes.Contains(operations, []byte("func (r *CountResponse) SoapEncodingStyle() string {")) {
+		t.Errorf("generated operations contain %q", "func (r *CountResponse) SoapEncodingStyle() string {")
+	}
+}
+
+func TestGenOperationsParts(t *testing.T) {
+	g, err := NewGoWsdl("fixtures/parts.wsdl", "myservice", false)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	gocode, _, err := g.Start()
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	operations := gocode["operations"]
+	for _, want := range []string{
+		// body parts
+		`{Name: xml.Name{Space: "http://example.com/bank", Local: "Amount"}, Value: r.Amount},`,
+		`{Name: xml.Name{Space: "http://example.com/bank", Local: "Balance"}, Value: &r.Balance},`,
+		// soap:body parts="query"
+		`{Name: xml.Name{Space: "http://example.com/bank", Local: "Query"}, Value: r.Query},`,
+	} {
+		if !bytes.Contains(operations, []byte(want)) {
+			t.Errorf("generated operations do not contain %q", want)
+		}
+	}
+	if bytes.Contains(operations, []byte("r.Trace")) {
+		t.Errorf("generated operations send the trace part, which is not a body part")
+	}
+}
+
+func TestGenOperationsHeaders(t *testing.T) {
+	g, err := NewGoWsdl("fixtures/parts.wsdl", "myservice", false)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	gocode, _, err := g.Start()
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	operations := gocode["operations"]
+	for _, want := range []string{
+		// parts of the message bound to soap:header
+		"type TransferRequestHeader struct",
+		`{Name: xml.Name{Space: "http://example.com/bank", Local: "Auth"}, Value: h.Auth},`,
+		`{Name: xml.Name{Space: "http://example.com/bank", Local: "Session"}, Value: &h.Session},`,
+		// soap:headerfault
+		`{Name: xml.Name{Space: "http://example.com/bank", Local: "AuthFault"}, Value: &h.Fault},`,
+		"return nil, responseHeader, fault",
+		// soap:header of another message
+		`{Name: xml.Name{Space: "http://example.com/bank", Local: "Trace"}, Value: h.Trace},`,
+		"requestHeader *StatementRequestHeader, header *gowsdl.SoapHeader",
+	} {
+		if !bytes.Contains(operations, []byte(want)) {
+			t.Errorf("generated operations do not contain %q", want)
+		}
+	}
+}
+
+func TestGenServer(t *testing.T) {
+	g, err := NewGoWsdl("fixtures/parts.wsdl", "myservice", false, GenerateServer())
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	gocode, _, err := g.Start()
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	server := gocode["server"]
+	for _, want := range []string{
+		"type BankServer interface",
+		"Transfer(ctx context.Context, request *TransferRequest, requestHeader *TransferRequestHeader) (*TransferResponse, *TransferResponseHeader, error)",
+		"func NewBankHandler(service BankServer) http.Handler",
+		`Action:  "http://example.com/bank/Transfer",`,
+		`Element: xml.Name{Space: "http://example.com/bank", Local: "Query"},`,
+		"return service.Transfer(ctx, request, requestHeader)",
+	} {
+		if !bytes.Contains(server, []byte(want)) {
+			t.Errorf("generated server does not contain %q", want)
+		}
+	}
+}
+
+func TestGenFake(t *testing.T) {
+	g, err := NewGoWsdl("fixtures/parts.wsdl", "myservice", false, GenerateFake())
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	gocode, _, err := g.Start()
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	if gocode["server"] == nil {
+		t.Errorf("fakes are generated without their server")
+	}
+	fake := gocode["fake"]
+	for _, want := range []string{
+		"type FakeBank struct",
+		"func NewFakeBankServer(fake *FakeBank) *httptest.Server",
+		"func (f *FakeBank) ReplyTransfer(response *TransferResponse, responseHeader *TransferResponseHeader)",
+		"func (f *FakeBank) FailStatement(err error)",
+		"func (f *FakeBank) TransferRequests() []*TransferRequest",
+		"func (f *FakeBank) TransferRequestHeaders() []*TransferRequestHeader",
+	} {
+		if !bytes.Contains(fake, []byte(want)) {
+			t.Errorf("generated fake does not contain %q", want)
+		}
+	}
+	if !bytes.Contains(gocode["header"], []byte(`"net/http/httptest"`)) {
+		t.Errorf("generated header does not import net/http/httptest")
+	}
+}
+
+func TestGenOperationsInterface(t *testing.T) {
+	g, err := NewGoWsdl("fixtures/rpc.wsdl", "myservice", false)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	gocode, _, err := g.Start()
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	operations := gocode["operations"]
+	for _, want := range []string{
+		"type InventoryInterface interface",
+		"Count(request *CountRequest, header *gowsdl.SoapHeader, configureRequest func(*http.Request)) (*CountResponse, error)",
+		"CountContext(ctx context.Context, request *CountRequest, header *gowsdl.SoapHeader, configureRequest func(*http.Request)) (*CountResponse, error)",
+		"var _ InventoryInterface = (*Inventory)(nil)",
+	} {
+		if !bytes.Contains(operations, []byte(want)) {
+			t.Errorf("generated operations do not contain %q", want)
+		}
+	}
+}
+
+func TestGenTypesNamespaces(t *testing.T) {
+	g, err := NewGoWsdl("fixtures/namespaces.wsdl", "myservice", false)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	gocode, gotypes, err := g.Start()
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	types := gotypes["basetypes"]
+	for _, want := range []string{
+		"Price *Money `xml:\"http://example.com/orders Price,omitempty\"`",
+		"Line []*Line `xml:\"http://example.com/orders Line,omitempty\"`",
+		"Note *Note `xml:\"http://example.com/common Note,omitempty\"`",
+		"XMLName xml.Name `xml:\"http://example.com/orders PlaceOrder\"`",
+	} {
+		if !bytes.Contains(types, []byte(want)) {
+			t.Errorf("generated types do not contain %q", want)
+		}
+	}
+
+	want := "PlaceOrder(request *basetypes.PlaceOrder, header *gowsdl.SoapHeader, configureRequest func(*http.Request)) (*basetypes.PlaceOrderResponse, error)"
+	if !bytes.Contains(gocode["operations"], []byte(want)) {
+		t.Errorf("generated operations do not contain %q", want)
+	}
+}
+
+func TestGenTypesNamedTypes(t *testing.T) {
+	g, err := NewGoWsdl("fixtures/shipping.wsdl", "myservice", false)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	_, gotypes, err := g.Start()
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	types := gotypes["basetypes"]
+	for _, want := range []string{
+		"From *Address `xml:\"http://example.com/shipping From,omitempty\"`",
+		"To *Address `xml:\"http://example.com/shipping To,omitempty\"`",
+		"XMLName xml.Name `xml:\"http://example.com/shipping Ship\"`",
+		"func (t *Address) SoapElementName() xml.Name",
+	} {
+		if !bytes.Contains(types, []byte(want)) {
+			t.Errorf("generated types do not contain %q", want)
+		}
+	}
+	// The fields name the elements of Address, even if it is the type of the
+	// Address element.
+	if want := "XMLName xml.Name `xml:\"http://example.com/shipping Address\"`"; bytes.Contains(types, []byte(want)) {
+		t.Errorf("generated types contain %q", want)
+	}
+}
+
+func TestGenTypesNamespaceCollisions(t *testing.T) {
+	g, err := NewGoWsdl("fixtures/contacts.wsdl", "myservice", false)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	_, gotypes, err := g.Start()
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	types := gotypes["basetypes"]
+	for _, want := range []string{
+		"type Address struct",
+		"type AddressB struct",
+		"type KindB string",
+		`KindBWork KindB = "work"`,
+		"Home *Address `xml:\"urn:b Home,omitempty\"`",
+		"Work *AddressB `xml:\"urn:b Work,omitempty\"`",
+		"Kind *KindB `xml:\"urn:b Kind,omitempty\"`",
+	} {
+		if !bytes.Contains(types, []byte(want)) {
+			t.Errorf("generated types do not contain %q", want)
+		}
+	}
+}
+
+func TestGenTypesForms(t *testing.T) {
+	g, err := NewGoWsdl("fixtures/forms.wsdl", "myservice", false)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	_, gotypes, err := g.Start()
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	types := gotypes["basetypes"]
+	for _, want := range []string{
+		"XMLName xml.Name `xml:\"http://example.com/accounts OpenAccount\"`",
+		"Owner string `xml:\"Owner,omitempty\"`",
+		"Branch string `xml:\"http://example.com/accounts Branch,omitempty\"`",
+		"XMLName xml.Name `xml:\"Limits\"`",
+		"Currency string `xml:\"http://example.com/accounts currency,attr,omitempty\"`",
+		"Channel string `xml:\"channel,attr,omitempty\"`",
+	} {
+		if !bytes.Contains(types, []byte(want)) {
+			t.Errorf("generated types do not contain %q", want)
+		}
+	}
+}
+
+func BenchmarkStart(b *testing.B) {
+	for _, fixture := range []string{"chromedata", "ec2", "ferry", "usda-awdb", "vboxweb"} {
+		b.Run(fixture, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				g, err := NewGoWsdl("fixtures/"+fixture+".wsdl", "myservice", false)
+				if err != nil {
+					b.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+				}
+				if _, _, err := g.Start(); err != nil {
+					b.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+				}
+			}
+		})
+	}
+}
+
+func TestGenTypesGroups(t *testing.T) {
+	g, err := NewGoWsdl("fixtures/groups.wsdl", "myservice", false)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	_, gotypes, err := g.Start()
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	types := gotypes["basetypes"]
+	for _, want := range []string{
+		"CreatedBy string `xml:\"createdBy,attr,omitempty\"`",
+		"Version int32 `xml:\"version,attr,omitempty\"`",
+		"Name string `xml:\"http://example.com/catalog Name,omitempty\"`",
+		"Dimensions *Dimensions `xml:\"http://example.com/catalog Dimensions,omitempty\"`",
+		"Amount float64 `xml:\"http://example.com/common Amount,omitempty\"`",
+		"Currency *Currency `xml:\"http://example.com/common Currency,omitempty\"`",
+		"type Dimensions struct",
+	} {
+		if !bytes.Contains(types, []byte(want)) {
+			t.Errorf("generated types do not contain %q", want)
+		}
+	}
+
+	for _, unwanted := range []string{"type AuditAttributes struct", "type PriceGroup struct"} {
+		if bytes.Contains(types, []byte(unwanted)) {
+			t.Errorf("generated types contain %q", unwanted)
+		}
+	}
+}
+
+func TestGenTypesChoices(t *testing.T) {
+	g, err := NewGoWsdl("fixtures/choices.wsdl", "myservice", false)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	_, gotypes, err := g.Start()
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	types := gotypes["basetypes"]
+	fields := []string{
+		"Amount float64 `xml:\"http://example.com/payments Amount,omitempty\"`",
+		"Card *Card `xml:\"http://example.com/payments Card,omitempty\"`",
+		"Iban string `xml:\"http://example.com/payments Iban,omitempty\"`",
+		"Bic string `xml:\"http://example.com/payments Bic,omitempty\"`",
+		"Reference string `xml:\"http://example.com/payments Reference,omitempty\"`",
+		"Choice []*PayChoice `xml:\",any\"`",
+	}
+	last := -1
+	for _, want := range fields {
+		i := bytes.Index(types, []byte(want))
+		if i < 0 {
+			t.Errorf("generated types do not contain %q", want)
+		} else if i < last {
+			t.Errorf("generated field %q is out of document order", want)
+		} else {
+			last = i
+		}
+	}
+
+	for _, want := range []string{
+		"func (t *Card) Validate() error",
+		"gowsdl.ChoiceBranch{Name: \"Token\", Fields: []interface{}{t.Token}}",
+		"func (t *Pay) Validate() error",
+		"gowsdl.ChoiceBranch{Name: \"(Iban, Bic)\", Fields: []interface{}{t.Iban, t.Bic}}",
+		"type PayChoice struct",
+		"Note string `xml:\"http://example.com/payments Note,omitempty\"`",
+		"return gowsdl.MarshalChoice(e, c)",
+		"return gowsdl.UnmarshalChoice(d, start, c)",
+		"func (c *PayChoice) Validate() error",
+	} {
+		if !bytes.Contains(types, []byte(want)) {
+			t.Errorf("generated types do not contain %q", want)
+		}
+	}
+}
+
+func TestGenTypesRepeatingChoices(t *testing.T) {
+	g, err := NewGoWsdl("fixtures/choices.wsdl", "myservice", false)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	_, gotypes, err := g.Start()
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	types := gotypes["basetypes"]
+	for _, want := range []string{
+		"Choice string `xml:\"http://example.com/payments Choice,omitempty\"`",
+		// Named after the Choice element.
+		"Choice2 []*BatchChoice2 `xml:\",any\"`",
+		"type BatchChoice2 struct",
+		"Credit float64 `xml:\"http://example.com/payments Credit,omitempty\"`",
+		"func (c *BatchChoice2) Validate() error",
+		"gowsdl.ValidateChoice(\"BatchChoice2\",",
+		// encoding/xml decodes the unknown elements into a single field.
+		"Memo []string `xml:\"http://example.com/payments Memo,omitempty\"`",
+		"Flag []string `xml:\"http://example.com/payments Flag,omitempty\"`",
+	} {
+		if !bytes.Contains(types, []byte(want)) {
+			t.Errorf("generated types do not contain %q", want)
+		}
+	}
+}
+
+func TestGenTypesParticles(t *testing.T) {
+	g, err := NewGoWsdl("fixtures/particles.wsdl", "myservice", false)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	_, gotypes, err := g.Start()
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	types := gotypes["basetypes"]
+	for _, want := range []string{
+		// sequence>sequence>choice>sequence>element
+		"CheckIn time.Time `xml:\"http://example.com/booking CheckIn,omitempty\"`",
+		"Nights int32 `xml:\"http://example.com/booking Nights,omitempty\"`",
+		"Period string `xml:\"http://example.com/booking Period,omitempty\"`",
+		// repeating sequence
+		"Room []string `xml:\"http://example.com/booking Room,omitempty\"`",
+		"Guest []*Passenger `xml:\"http://example.com/booking Guest,omitempty\"`",
+		// choice of a group referenced by a complex type
+		"Email string `xml:\"http://example.com/booking Email,omitempty\"`",
+		"Phone string `xml:\"http://example.com/booking Phone,omitempty\"`",
+		"gowsdl.ChoiceBranch{Name: \"(CountryCode, Phone)\", Fields: []interface{}{t.CountryCode, t.Phone}}",
+		// extension>sequence>choice>sequence>element
+		"Passport string `xml:\"http://example.com/booking Passport,omitempty\"`",
+		"func (t *Passenger) Validate() error",
+	} {
+		if !bytes.Contains(types, []byte(want)) {
+			t.Errorf("generated types do not contain %q", want)
+		}
+	}
+}
+
+func TestGenTypesInheritance(t *testing.T) {
+	g, err := NewGoWsdl("fixtures/inheritance.wsdl", "myservice", false)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	_, gotypes, err := g.Start()
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	types := gotypes["basetypes"]
+	for _, want := range []string{
+		// abstract base
+		"type ShapeInterface interface {",
+		"IsShape()",
+		"func (t *Shape) IsShape() {}",
+		// extensions embed their base by value
+		"Opacity float64 `xml:\"opacity,attr,omitempty\"`",
+		// restriction
+		"func (t *Point) IsShape() {}",
+		// fields named otherwise than their type
+		"Main *AnyShape `xml:\"http://example.com/drawing Main,omitempty\"`",
+		"Origin *Point `xml:\"http://example.com/drawing Origin,omitempty\"`",
+	} {
+		if !bytes.Contains(types, []byte(want)) {
+			t.Errorf("generated types do not contain %q", want)
+		}
+	}
+	for _, notWant := range []string{"*Shape\n", "*Circle\n"} {
+		if bytes.Contains(types, []byte(notWant)) {
+			t.Errorf("generated types contain %q", notWant)
+		}
+	}
+
+	// the layer attribute is prohibited in Point
+	if got := bytes.Count(types, []byte("Layer int32")); got != 1 {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", got, 1)
+	}
+}
+
+func TestGenTypesXsiType(t *testing.T) {
+	g, err := NewGoWsdl("fixtures/inheritance.wsdl", "myservice", false)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	_, gotypes, err := g.Start()
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	types := gotypes["basetypes"]
+	for _, want := range []string{
+		// holders of the polymorphic types, abstract or not
+		"type AnyShape struct {",
+		"Value ShapeInterface",
+		"return gowsdl.UnmarshalXsiType(d, start, &a.Value, func() interface{} { return new(Shape) })",
+		"type CircleInterface interface {",
+		"type AnyCircle struct {",
+		"func (t *Point) IsShape() {}",
+		// fields of polymorphic types
+		"Circle *AnyCircle `xml:\"http://example.com/drawing Circle,omitempty\"`",
+		"Shape []*AnyShape `xml:\"http://example.com/drawing Shape,omitempty\"`",
+		"Point *Point `xml:\"http://example.com/drawing Point,omitempty\"`",
+		// registry
+		"gowsdl.RegisterTypes(gowsdl.TypeRegistry{",
+		"{Space: \"http://example.com/drawing\", Local: \"FilledCircle\"}: func() interface{} { return new(FilledCircle) },",
+		"{Space: \"http://example.com/drawing\", Local: \"Point\"}: func() interface{} { return new(Point) },",
+	} {
+		if !bytes.Contains(types, []byte(want)) {
+			t.Errorf("generated types do not contain %q", want)
+		}
+	}
+	for _, notWant := range []string{"type AnyFilledCircle struct", "type AnyPoint struct"} {
+		if bytes.Contains(types, []byte(notWant)) {
+			t.Errorf("generated types contain %q", notWant)
+		}
+	}
+}
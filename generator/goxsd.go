@@ -37,6 +37,8 @@ type GoXsd struct {
 	processedSimpleTypes  map[string]map[string]bool
 	packagesTypes 	  	  map[string]map[string]bool
 	currentSchema	      *XsdSchema
+	symbols               *symbolTable
+	schemaScopes          []*XsdSchema
 }
 
 func NewGoXsd(file, pkg string, ignoreTls bool) (*GoXsd, error) {
@@ -67,6 +69,7 @@ func (g *GoXsd) Start() (map[string][]byte, error) {
 	}
 
 	g.fillPackagesTypes()
+	g.symbols = newSchemasSymbolTable(getSchemaName(g.file), []*XsdSchema{g.xsd}, g.resolvedXsdExternals)
 
 	var wg sync.WaitGroup
 
@@ -246,40 +249,7 @@ func (g *GoXsd) fillComplexTypesLocal(elementType *XsdElement) {
 	g.addPackageType(elType)
 
 	if(elementType.ComplexType != nil){
-		if(elementType.ComplexType.ComplexContent.Extension.Base != ""){
-			for _, el := range elementType.ComplexType.ComplexContent.Extension.Sequence {
-				if(el.Ref == "" && el.SimpleType == nil && el.Type == ""){
-					g.fillComplexTypesLocal(&el)
-				}
-			}
-		}else if(elementType.ComplexType.SimpleContent.Extension.Base != ""){
-			for _, el := range elementType.ComplexType.SimpleContent.Extension.Sequence {
-				if(el.Ref == "" && el.SimpleType == nil && el.Type == ""){
-					g.fillComplexTypesLocal(&el)
-				}
-			}
-		}else{
-			for _, el := range elementType.ComplexType.Sequence {
-				if(el.Ref == "" && el.SimpleType == nil && el.Type == ""){
-					g.fillComplexTypesLocal(&el)
-				}
-			}
-			for _, el := range elementType.ComplexType.SubSequence {
-				if(el.Ref == "" && el.SimpleType == nil && el.Type == ""){
-					g.fillComplexTypesLocal(&el)
-				}
-			}
-			for _, el := range elementType.ComplexType.Choice {
-				if(el.Ref == "" && el.SimpleType == nil && el.Type == ""){
-					g.fillComplexTypesLocal(&el)
-				}
-			}
-			for _, el := range elementType.ComplexType.All {
-				if(el.Ref == "" && el.SimpleType == nil && el.Type == ""){
-					g.fillComplexTypesLocal(&el)
-				}
-			}
-		}
+		g.fillContentTypesLocal(elementType.ComplexType)
 	}
 	return
 }
@@ -288,41 +258,22 @@ func (g *GoXsd) fillComplexTypesGlobal(complexType *XsdComplexType) {
 	elType := makePublic(replaceReservedWords(complexType.Name))
 	g.addPackageType(elType)
 
-	if(complexType.ComplexContent.Extension.Base != ""){
-		for _, el := range complexType.ComplexContent.Extension.Sequence {
-			if(el.Ref == "" && el.SimpleType == nil && el.Type == ""){
-				g.fillComplexTypesLocal(&el)
-			}
-		}
-	}else if(complexType.SimpleContent.Extension.Base != ""){
-		for _, el := range complexType.SimpleContent.Extension.Sequence {
-			if(el.Ref == "" && el.SimpleType == nil && el.Type == ""){
-				g.fillComplexTypesLocal(&el)
-			}
-		}
-	}else{
-		for _, el := range complexType.Sequence {
-			if(el.Ref == "" && el.SimpleType == nil && el.Type == ""){
-				g.fillComplexTypesLocal(&el)
-			}
-		}
-		for _, el := range complexType.SubSequence {
-			if(el.Ref == "" && el.SimpleType == nil && el.Type == ""){
-				g.fillComplexTypesLocal(&el)
-			}
-		}
-		for _, el := range complexType.Choice {
-			if(el.Ref == "" && el.SimpleType == nil && el.Type == ""){
-				g.fillComplexTypesLocal(&el)
-			}
-		}
-		for _, el := range complexType.All {
-			if(el.Ref == "" && el.SimpleType == nil && el.Type == ""){
-				g.fillComplexTypesLocal(&el)
-			}
+	g.fillContentTypesLocal(complexType)
+	return
+}
+
+// Adds the types of the local elements of the content of complexType, or of
+// the one of its extension.
+func (g *GoXsd) fillContentTypesLocal(complexType *XsdComplexType) {
+	content := complexType.Content()
+	if complexType.ComplexContent.Extension.Base != "" {
+		content = complexType.ComplexContent.Extension.Content()
+	}
+	for _, el := range content.elements() {
+		if el.Ref == "" && el.SimpleType == nil && el.Type == "" {
+			g.fillComplexTypesLocal(el)
 		}
 	}
-	return
 }
 
 func (g *GoXsd) fillSchemaTypes(schema *XsdSchema) {
@@ -382,6 +333,24 @@ func (g *GoXsd) genTypes() (map[string][]byte, error) {
 		"toGoUnionType":        toGoUnionType,
 		"isBaseType":			isBaseType,
 		"findType":             g.findType,
+		"qualifiedName":        g.qualifiedName,
+		"findGroup":            g.findGroup,
+		"findAttributeGroup":   g.findAttributeGroup,
+		"pushSchemaScope":      g.pushSchemaScope,
+		"popSchemaScope":       g.popSchemaScope,
+		"elementName":          g.elementName,
+		"attributeName":        g.attributeName,
+		"contentChoices":       g.contentChoices,
+		"ancestors":            g.ancestors,
+		"isPolymorphic":        g.isPolymorphic,
+		"typeName":             g.typeName,
+		"elementTypeName":      g.elementTypeName,
+		"typeIsElement":        g.typeIsElement,
+		"polymorphicType":      g.polymorphicType,
+		"xsiTypes":             g.xsiTypes,
+		"isSoapArrayBase":      g.isSoapArrayBase,
+		"replaceStar":          replaceStar,
+		"elementList":          elementList,
 		"stripns":              stripns,
 		"replaceReservedWords": replaceReservedWords,
 		"processComplexType" :  g.processComplexType,
@@ -535,11 +504,133 @@ func (g *GoXsd) targetNamspace() string {
 	}
 }
 
-// Given a message, finds its type.
-//
-// I'm not very proud of this function but
-// it works for now and performance doesn't
-// seem critical at this point
+// Makes the names of the definitions being generated resolve in schema, the
+// one of a group being inlined, until the matching popSchemaScope.
+func (g *GoXsd) pushSchemaScope(schema *XsdSchema) string {
+	g.schemaScopes = append(g.schemaScopes, schema)
+	return ""
+}
+
+func (g *GoXsd) popSchemaScope() string {
+	g.schemaScopes = g.schemaScopes[:len(g.schemaScopes)-1]
+	return ""
+}
+
+// Returns the schema declaring the definitions being generated, the one of
+// the innermost group being inlined, if any, or the current schema.
+func (g *GoXsd) scopeSchema() *XsdSchema {
+	if n := len(g.schemaScopes); n > 0 {
+		return g.schemaScopes[n-1]
+	}
+	return g.currentSchema
+}
+
+// Finds the model group referenced by ref, or nil.
+func (g *GoXsd) findGroup(ref string) *schemaDecl {
+	decl := g.symbols.group(g.scopeSchema().resolveQName(ref))
+	if decl == nil {
+		Log.Warn("Group not found", "ref", ref)
+	}
+	return decl
+}
+
+// Finds the attribute group referenced by ref, or nil.
+func (g *GoXsd) findAttributeGroup(ref string) *schemaDecl {
+	decl := g.symbols.attributeGroup(g.scopeSchema().resolveQName(ref))
+	if decl == nil {
+		Log.Warn("Attribute group not found", "ref", ref)
+	}
+	return decl
+}
+
+// Returns the choices of the content model of the complex type being
+// generated.
+func (g *GoXsd) contentChoices(content *XsdCompositor) *contentChoices {
+	return g.symbols.contentChoices(g, content)
+}
+
+// Returns the base type of a restriction of the schema being generated and
+// the types it derives from.
+func (g *GoXsd) ancestors(base string) []*schemaDecl {
+	return g.symbols.ancestors(g.scopeSchema(), base)
+}
+
+// Returns the Go name of the global type local of the schema being
+// generated.
+func (g *GoXsd) typeName(local string) string {
+	return g.symbols.goTypeName(g.scopeSchema(), local, false)
+}
+
+// Reports whether the global type local of the schema being generated is
+// also generated for the global element of the same name.
+func (g *GoXsd) typeIsElement(local string) bool {
+	return g.symbols.typeIsElement(g.scopeSchema(), local)
+}
+
+// Returns the Go name of the global element local of the schema being
+// generated.
+func (g *GoXsd) elementTypeName(local string) string {
+	return g.symbols.goTypeName(g.scopeSchema(), local, true)
+}
+
+// Reports whether complexType, a global complex type of the schema being
+// generated, is abstract or has derived types.
+func (g *GoXsd) isPolymorphic(complexType *XsdComplexType) bool {
+	return g.symbols.polymorphic(g.symbols.complexType(g.scopeSchema(), complexType))
+}
+
+// Returns the holder of the values of xmlType, a type of the schema being
+// generated, when it is polymorphic, or "".
+func (g *GoXsd) polymorphicType(xmlType string) string {
+	decl := g.symbols.lookup(g.scopeSchema().resolveQName(xmlType), false)
+	if !g.symbols.polymorphic(decl) {
+		return ""
+	}
+	if decl.Pkg == g.currentSchema.Parent {
+		return "*Any" + decl.GoName
+	}
+	g.importsNeeded[replaceReservedWords(decl.Pkg)] = true
+	return decl.anyType()
+}
+
+// Returns the complex types of the schema being generated registered for
+// xsi:type.
+func (g *GoXsd) xsiTypes() []*schemaDecl {
+	return g.symbols.xsiTypes(g.scopeSchema())
+}
+
+// Reports whether base, the base type of a restriction of the schema being
+// generated, is soapenc:Array.
+func (g *GoXsd) isSoapArrayBase(base string) bool {
+	return isSoapArray(g.scopeSchema().resolveQName(base))
+}
+
+// Returns the struct tag name of a local element of the schema being
+// generated, see XsdSchema.elementName.
+func (g *GoXsd) elementName(name, form string) string {
+	return g.scopeSchema().elementName(name, form)
+}
+
+// Returns the struct tag name of a local attribute of the schema being
+// generated, see XsdSchema.attributeName.
+func (g *GoXsd) attributeName(name, form string) string {
+	return g.scopeSchema().attributeName(name, form)
+}
+
+// Returns the namespace and local name of an element reference of the
+// schema being generated, as used in the xml struct tag of its field, or ""
+// if no schema declares it.
+func (g *GoXsd) qualifiedName(ref string) string {
+	name := g.scopeSchema().resolveQName(ref)
+	if name.Space == "" || g.symbols.elements.names[name] == nil {
+		return ""
+	}
+	return name.Space + " " + name.Local
+}
+
+// Finds the Go type of a type, element or attribute group reference of the
+// schema being generated, qualified with its package when it is generated in
+// another one.
 func (g *GoXsd) findType(xmlType string) string {
 	elRef := makePublic(replaceReservedWords(stripns(xmlType)))
 
@@ -556,32 +647,23 @@ func (g *GoXsd) findType(xmlType string) string {
 		return toGoType(replaceReservedWords(xmlType))
 	}
 
-	for keyType, _ := range g.packagesTypes[g.currentSchema.Parent] {
-		if(elRef == keyType){
-//			if(xmlType == "RPH_Type"){
-//				Log.Info("INNER")
-//			}
-			//Log.Info("FOUND INNER TYPE "+elRef)
-			fullname := "*"+makePublic(replaceReservedWords(elRef))
-			return fullname
+	if decl := g.symbols.lookup(g.scopeSchema().resolveQName(xmlType), false); decl != nil {
+		if decl.Pkg == g.currentSchema.Parent {
+			return "*" + decl.GoName
 		}
+		g.importsNeeded[replaceReservedWords(decl.Pkg)] = true
+		return decl.goType()
 	}
 
-
+	// Types of local elements, which the symbol table doesn't index.
+	if g.packagesTypes[g.currentSchema.Parent][elRef] {
+		return "*" + elRef
+	}
 	for keyPkg, elPkg := range g.packagesTypes {
-		pkg := replaceReservedWords(keyPkg)
-		for keyType, _ := range elPkg {
-			if(elRef == keyType){
-//				if(xmlType == "RPH_Type"){
-//					Log.Info("PKG")
-//				}
-				//Log.Info("FOUND TYPE "+pkg+"."+elRef)
-				if(!g.importsNeeded[pkg]){
-					g.importsNeeded[pkg] = true
-				}
-				fullname := "*"+pkg+"."+makePublic(replaceReservedWords(elRef))
-				return fullname
-			}
+		if elPkg[elRef] {
+			pkg := replaceReservedWords(keyPkg)
+			g.importsNeeded[pkg] = true
+			return "*" + pkg + "." + elRef
 		}
 	}
 
@@ -9,7 +9,9 @@ package {{.Pkg}}
 // Do not modify
 // Copyright (c) 2015, Hooklift. All rights reserved.
 import (
+	"context"
     "net/http"
+	{{if .Fake}}"net/http/httptest"{{end}}
 	"encoding/xml"
 	"time"
 
@@ -15,9 +15,12 @@ import (
 	{{ range $key, $value := .ImportsNeeded }}
 		"{{ $pkgBase }}/{{ getSchemaName $key | replaceReservedWords }}"
 	{{end}}
+
+	gowsdl "github.com/hooklift/gowsdl/generator"
 )
 
 // against "unused imports"
 var _ time.Time
 var _ xml.Name
+var _ gowsdl.Base64Binary
 `
@@ -0,0 +1,230 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const XopNamespace = "http://www.w3.org/2004/08/xop/include"
+
+const xopMediaType = "application/xop+xml"
+
+// Base64Binary is the Go type of xs:base64Binary. It is sent base64 encoded
+// inside the envelope or, by clients using WithMTOM, as a binary MTOM
+// attachment referenced by an xop:Include.
+type Base64Binary []byte
+
+// Attachments of the message being marshalled by an encoder, Base64Binary
+// values add themselves to them instead of being inlined.
+var mtomEncoders sync.Map // *xml.Encoder -> *mimeParts
+
+type xopInclude struct {
+	XMLName xml.Name `xml:"http://www.w3.org/2004/08/xop/include Include"`
+	Href    string   `xml:"href,attr"`
+}
+
+func (b Base64Binary) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if parts, ok := mtomEncoders.Load(e); ok && len(b) > 0 {
+		contentID := parts.(*mimeParts).add("application/octet-stream", b)
+		return e.EncodeElement(struct{ Include xopInclude }{xopInclude{Href: "cid:" + contentID}}, start)
+	}
+	return e.EncodeElement(base64.StdEncoding.EncodeToString(b), start)
+}
+
+func (b Base64Binary) MarshalText() ([]byte, error) {
+	return []byte(base64.StdEncoding.EncodeToString(b)), nil
+}
+
+func (b *Base64Binary) UnmarshalText(text []byte) error {
+	data, err := decodeBase64(string(text))
+	if err != nil {
+		return err
+	}
+	*b = data
+	return nil
+}
+
+// Attachment is a part of a multipart/related message, identified by its
+// Content-ID without the angle brackets.
+type Attachment struct {
+	ContentID   string
+	ContentType string
+	Data        []byte
+}
+
+type mimeParts struct {
+	parts []*Attachment
+}
+
+func (p *mimeParts) add(contentType string, data []byte) string {
+	contentID := fmt.Sprintf("part%d@gowsdl", len(p.parts)+1)
+	p.parts = append(p.parts, &Attachment{ContentID: contentID, ContentType: contentType, Data: data})
+	return contentID
+}
+
+// Marshals the request, Base64Binary values being collected as attachments
+// when mtom is set.
+func marshalRequest(request interface{}, mtom bool) (string, *mimeParts, error) {
+	buffer := &bytes.Buffer{}
+	encoder := xml.NewEncoder(buffer)
+
+	parts := &mimeParts{}
+	if mtom {
+		mtomEncoders.Store(encoder, parts)
+		defer mtomEncoders.Delete(encoder)
+	}
+
+	err := encoder.Encode(namedElement(request))
+	if err == nil {
+		err = encoder.Flush()
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return buffer.String(), parts, nil
+}
+
+// Packages an envelope and its attachments in a multipart/related XOP
+// message and returns it along with its content type. envelopeType is the
+// media type of the envelope, ie. text/xml or application/soap+xml, action
+// the SOAP 1.2 action, if any.
+func newMTOMMessage(envelope []byte, parts *mimeParts, envelopeType, action string) (*bytes.Buffer, string, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	rootType := xopMediaType + `; charset=UTF-8; type="` + envelopeType + `"`
+	if action != "" {
+		rootType += `; action="` + action + `"`
+	}
+
+	root := &Attachment{ContentID: "root@gowsdl", ContentType: rootType, Data: envelope}
+	for _, part := range append([]*Attachment{root}, parts.parts...) {
+		w, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.ContentType},
+			"Content-Transfer-Encoding": {"binary"},
+			"Content-Id":                {"<" + part.ContentID + ">"},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := w.Write(part.Data); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+
+	contentType := mime.FormatMediaType("multipart/related", map[string]string{
+		"type":       xopMediaType,
+		"start":      "<" + root.ContentID + ">",
+		"start-info": envelopeType,
+		"boundary":   writer.Boundary(),
+	})
+	if action != "" {
+		contentType += `; action="` + action + `"`
+	}
+	return body, contentType, nil
+}
+
+// Splits a multipart/related message into its root part, the one named by
+// the start parameter or else the first one, and the other parts.
+func parseMultipartRelated(data []byte, params map[string]string) (*Attachment, []*Attachment, error) {
+	boundary := params["boundary"]
+	if boundary == "" {
+		return nil, nil, errors.New("multipart response without boundary")
+	}
+	start := strings.Trim(params["start"], "<>")
+
+	var root *Attachment
+	var parts []*Attachment
+
+	reader := multipart.NewReader(bytes.NewReader(data), boundary)
+	for {
+		p, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		data, err := ioutil.ReadAll(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		if strings.EqualFold(p.Header.Get("Content-Transfer-Encoding"), "base64") {
+			if data, err = decodeBase64(string(data)); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		part := &Attachment{
+			ContentID:   strings.Trim(p.Header.Get("Content-Id"), "<>"),
+			ContentType: p.Header.Get("Content-Type"),
+			Data:        data,
+		}
+		if root == nil && (start == "" || part.ContentID == start) {
+			root = part
+		} else {
+			parts = append(parts, part)
+		}
+	}
+
+	if root == nil {
+		return nil, nil, errors.New("multipart response without root part")
+	}
+	return root, parts, nil
+}
+
+// Replaces the xop:Include elements of an XOP envelope with the base64
+// encoded content of the parts they reference.
+func resolveXOP(envelope []byte, parts []*Attachment) ([]byte, error) {
+	root, err := parseXMLElement(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	var resolveErr error
+	root.walk(func(el *xmlElement) {
+		include := el.child(XopNamespace, "Include")
+		if include == nil || resolveErr != nil {
+			return
+		}
+
+		href, _ := include.attr("", "href")
+		// cid: URLs are escaped like URL paths (RFC 2392)
+		contentID := strings.TrimPrefix(href, "cid:")
+		if unescaped, err := url.PathUnescape(contentID); err == nil {
+			contentID = unescaped
+		}
+		for _, part := range parts {
+			if part.ContentID == contentID {
+				el.Children = []interface{}{xmlText(base64.StdEncoding.EncodeToString(part.Data))}
+				return
+			}
+		}
+		resolveErr = fmt.Errorf("xop:Include references a missing part %q", href)
+	})
+	if resolveErr != nil {
+		return nil, resolveErr
+	}
+
+	buffer := &bytes.Buffer{}
+	root.writeTo(buffer)
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,110 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type uploadRequest struct {
+	XMLName xml.Name     `xml:"http://example.com/documents Upload"`
+	Name    string       `xml:"Name"`
+	Content Base64Binary `xml:"Content"`
+}
+
+type downloadResponse struct {
+	XMLName xml.Name     `xml:"http://example.com/documents DownloadResponse"`
+	Name    string       `xml:"Name"`
+	Content Base64Binary `xml:"Content"`
+}
+
+// Not valid as XML character data, so it can only be sent base64 encoded or
+// as an attachment.
+var binaryContent = []byte{0x00, 0xff, 0x10, '<', '&', 0x01}
+
+const mtomResponse = "--MIMEBoundary\r\n" +
+	"Content-Type: application/xop+xml; charset=UTF-8; type=\"text/xml\"\r\n" +
+	"Content-Transfer-Encoding: binary\r\n" +
+	"Content-ID: <root.message@example.com>\r\n" +
+	"\r\n" +
+	`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>` +
+	`<DownloadResponse xmlns="http://example.com/documents"><Name>report.bin</Name>` +
+	`<Content><xop:Include xmlns:xop="http://www.w3.org/2004/08/xop/include" href="cid:content%40example.com"/></Content>` +
+	"</DownloadResponse></soap:Body></soap:Envelope>\r\n" +
+	"--MIMEBoundary\r\n" +
+	"Content-Type: application/octet-stream\r\n" +
+	"Content-Transfer-Encoding: binary\r\n" +
+	"Content-ID: <content@example.com>\r\n" +
+	"\r\n" +
+	"\x00\xff\x10<&\x01\r\n" +
+	"--MIMEBoundary--\r\n"
+
+func TestBase64Binary(t *testing.T) {
+	data, err := xml.Marshal(&uploadRequest{Name: "report.bin", Content: binaryContent})
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	if !bytes.Contains(data, []byte("<Content>AP8QPCYB</Content>")) {
+		t.Errorf("content is not base64 encoded: %s", data)
+	}
+
+	request := &uploadRequest{}
+	if err := xml.Unmarshal(data, request); err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	if !bytes.Equal(request.Content, binaryContent) {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", request.Content, binaryContent)
+	}
+}
+
+func TestWithMTOM(t *testing.T) {
+	var root *Attachment
+	var parts []*Attachment
+	var parseErr error
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "multipart/related" || params["type"] != "application/xop+xml" {
+			t.Errorf("incorrect content type %q", r.Header.Get("Content-Type"))
+		}
+		root, parts, parseErr = parseMultipartRelated(data, params)
+
+		w.Header().Set("Content-Type", `multipart/related; type="application/xop+xml"; boundary=MIMEBoundary; start="<root.message@example.com>"; start-info="text/xml"`)
+		w.Write([]byte(mtomResponse))
+	}))
+	defer ts.Close()
+
+	client := NewSoapClient(ts.URL, false, WithMTOM())
+
+	response := &downloadResponse{}
+	err := client.Call("", &uploadRequest{Name: "report.bin", Content: binaryContent}, response, nil, nil)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	if parseErr != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", parseErr, nil)
+	}
+	if !strings.HasPrefix(root.ContentType, "application/xop+xml") {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", root.ContentType, "application/xop+xml")
+	}
+	if len(parts) != 1 || !bytes.Equal(parts[0].Data, binaryContent) {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", parts, binaryContent)
+	}
+	include := `<Content><Include xmlns="http://www.w3.org/2004/08/xop/include" href="cid:` + parts[0].ContentID + `"></Include></Content>`
+	if !bytes.Contains(root.Data, []byte(include)) {
+		t.Errorf("root part does not reference the attachment: %s", root.Data)
+	}
+
+	if response.Name != "report.bin" || !bytes.Equal(response.Content, binaryContent) {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", response.Content, binaryContent)
+	}
+}
@@ -0,0 +1,187 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	gowsdl "github.com/hooklift/gowsdl/generator"
+	"github.com/hooklift/gowsdl/generator/testdata/faults"
+	"github.com/hooklift/gowsdl/generator/testdata/faults/basetypes"
+	"github.com/hooklift/gowsdl/generator/testdata/shipping"
+	shippingtypes "github.com/hooklift/gowsdl/generator/testdata/shipping/basetypes"
+)
+
+// Returns a server answering every request with a fault with the given
+// detail.
+func newFaultServer(detail string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+			<soap:Body><soap:Fault>
+				<faultcode>soap:Client</faultcode>
+				<faultstring>No such account</faultstring>
+				<detail>` + detail + `</detail>
+			</soap:Fault></soap:Body>
+		</soap:Envelope>`))
+	}))
+}
+
+func TestOperationsTypedFaults(t *testing.T) {
+	tests := []struct {
+		detail string
+		want   reflect.Type
+		id     string
+	}{
+		{
+			`<acc:InvalidId xmlns:acc="http://example.com/accounts"><acc:Id>x</acc:Id></acc:InvalidId>`,
+			reflect.TypeOf(&faults.InvalidIdFault{}), "x",
+		},
+		// Same content, told apart by the detail element.
+		{
+			`<NotFound xmlns="http://example.com/accounts"><Id>42</Id></NotFound>`,
+			reflect.TypeOf(&faults.NotFoundFault{}), "42",
+		},
+		// Detail of no fault of the operation.
+		{
+			`<NotFound xmlns="http://example.com/other"><Id>42</Id></NotFound>`,
+			reflect.TypeOf(&gowsdl.SoapFault{}), "",
+		},
+	}
+
+	for _, test := range tests {
+		ts := newFaultServer(test.detail)
+		service := faults.NewAccounts(ts.URL, false)
+		_, err := service.GetAccount(&basetypes.GetAccount{Id: "42"}, nil, nil)
+		ts.Close()
+
+		if reflect.TypeOf(err) != test.want {
+			t.Errorf("incorrect result\ngot:  %#v\nwant: %v", err, test.want)
+			continue
+		}
+
+		var fault *gowsdl.SoapFault
+		var id string
+		switch err := err.(type) {
+		case *faults.InvalidIdFault:
+			fault, id = err.SoapFault, err.FaultDetail.Id
+		case *faults.NotFoundFault:
+			fault, id = err.SoapFault, err.FaultDetail.Id
+		case *gowsdl.SoapFault:
+			fault = err
+		}
+
+		if id != test.id {
+			t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", id, test.id)
+		}
+		// The embedded fault and its raw detail stay accessible.
+		if fault == nil || fault.Faultstring != "No such account" || fault.Detail == "" {
+			t.Errorf("incorrect result\ngot:  %#v", fault)
+		}
+	}
+}
+
+type shippingService struct{}
+
+func (shippingService) Ship(ctx context.Context, request *shippingtypes.Ship) (*shippingtypes.ShipResponse, error) {
+	return &shippingtypes.ShipResponse{Tracking: request.From.City + "-" + request.To.City}, nil
+}
+
+func (shippingService) CheckAddress(ctx context.Context, request *shippingtypes.Address) (*shippingtypes.CheckAddressResponse, error) {
+	return &shippingtypes.CheckAddressResponse{Valid: request.City != ""}, nil
+}
+
+func TestOperationsElementOfNamedType(t *testing.T) {
+	// Address is both the type of the From and To fields and of the Address
+	// element.
+	ship := &shippingtypes.Ship{
+		From: &shippingtypes.Address{Street: "1 Main St", City: "Seattle"},
+		To:   &shippingtypes.Address{Street: "2 Pier Rd", City: "Bremerton"},
+	}
+	data, err := xml.Marshal(ship)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	got := &shippingtypes.Ship{}
+	if err := xml.Unmarshal(data, got); err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	ship.XMLName = xml.Name{Space: "http://example.com/shipping", Local: "Ship"}
+	if !reflect.DeepEqual(got, ship) {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", got, ship)
+	}
+
+	ts := httptest.NewServer(shipping.NewShippingHandler(shippingService{}))
+	defer ts.Close()
+	service := shipping.NewShipping(ts.URL, false)
+
+	shipped, err := service.Ship(ship, nil, nil)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	if shipped.Tracking != "Seattle-Bremerton" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", shipped.Tracking, "Seattle-Bremerton")
+	}
+
+	// Sent as the Address element, the server dispatching on it without the
+	// SOAP action.
+	var request []byte
+	checked, err := service.CheckAddress(ship.To, nil, func(r *http.Request) {
+		r.Header.Del("SOAPAction")
+		request, _ = ioutil.ReadAll(r.Body)
+		r.Body = ioutil.NopCloser(bytes.NewReader(request))
+	})
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	if !checked.Valid {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", checked.Valid, true)
+	}
+	if want := `<Address xmlns="http://example.com/shipping">`; !bytes.Contains(request, []byte(want)) {
+		t.Errorf("incorrect result\ngot:  %s\nwant: %s", request, want)
+	}
+}
+
+func TestServerEnvelopePrefixes(t *testing.T) {
+	ts := httptest.NewServer(shipping.NewShippingHandler(shippingService{}))
+	defer ts.Close()
+
+	// The prefix of the request is declared by the Envelope, as sent by
+	// SoapUI or JAX-WS.
+	request := `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:shp="http://example.com/shipping">
+		<soapenv:Header/>
+		<soapenv:Body><shp:Ship>
+			<shp:From><shp:City>Paris</shp:City></shp:From>
+			<shp:To><shp:City>Lyon</shp:City></shp:To>
+		</shp:Ship></soapenv:Body>
+	</soapenv:Envelope>`
+
+	// Dispatched on the SOAP action, then on the body element.
+	for _, action := range []string{`"http://example.com/shipping/Ship"`, ""} {
+		r, err := http.NewRequest("POST", ts.URL, strings.NewReader(request))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Content-Type", "text/xml")
+		r.Header.Set("SOAPAction", action)
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(data), ">Paris-Lyon</Tracking>") {
+			t.Errorf("incorrect result for action %q\ngot:  %d %s\nwant: %s", action, resp.StatusCode, data, "Paris-Lyon")
+		}
+	}
+}
@@ -5,44 +5,220 @@ package generator
 
 var opsTmpl = `
 {{range .}}
+{{if hasSoapBinding .Name}}
+	{{$portTypeName := .Name}}
 	{{$portType := .Name | makePublic}}
 	type {{$portType}} struct {
 		client *gowsdl.SoapClient
 	}
 
-	func New{{$portType}}(url string, tls bool) *{{$portType}} {
+	func New{{$portType}}(url string, tls bool, opts ...gowsdl.ClientOption) *{{$portType}} {
 		if url == "" {
 			url = {{findServiceAddress .Name | printf "%q"}}
 		}
-		client := gowsdl.NewSoapClient(url, tls)
+		{{if usesAddressing .Name}}
+		// The binding uses WS-Addressing, gowsdl.WithoutWSAddressing() turns it off.
+		opts = append([]gowsdl.ClientOption{gowsdl.WithWSAddressing()}, opts...)
+		{{end}}
+		client := gowsdl.New{{if isSoap12 .Name}}Soap12{{else}}Soap{{end}}Client(url, tls, opts...)
 
 		return &{{$portType}}{
 			client: client,
 		}
 	}
 
+	// {{$portType}}Interface lists the operations of {{$portType}}, ie. to mock it
+	// in tests.
+	type {{$portType}}Interface interface {
+		{{range .Operations}}
+		{{$sig := operationSignature . $portTypeName}}
+		{{if ne .Doc ""}}/* {{.Doc}} */{{end}}
+		{{$sig.Method}}({{if ne $sig.Request ""}}request {{$sig.Request}}, {{end}}{{if ne $sig.RequestHeader ""}}requestHeader {{$sig.RequestHeader}}, {{end}}header *gowsdl.SoapHeader, configureRequest func(*http.Request)) ({{$sig.Response}}, {{if ne $sig.ResponseHeader ""}}{{$sig.ResponseHeader}}, {{end}}error)
+		{{$sig.Method}}Context(ctx context.Context, {{if ne $sig.Request ""}}request {{$sig.Request}}, {{end}}{{if ne $sig.RequestHeader ""}}requestHeader {{$sig.RequestHeader}}, {{end}}header *gowsdl.SoapHeader, configureRequest func(*http.Request)) ({{$sig.Response}}, {{if ne $sig.ResponseHeader ""}}{{$sig.ResponseHeader}}, {{end}}error)
+		{{end}}
+	}
+
+	var _ {{$portType}}Interface = (*{{$portType}})(nil)
+
 	{{range .Operations}}
+		{{$typedFaults := typedFaults .Faults}}
+		{{range $typedFaults}}
+			{{if processFault .Message}}
+				{{$faultType := faultTypeName .Message}}
+				{{$detailType := findFaultType .Message}}
+				// {{$faultType}} is returned when the service answers with a fault
+				// whose detail is a {{replaceStar $detailType}}.
+				type {{$faultType}} struct {
+					*gowsdl.SoapFault
+					FaultDetail {{$detailType}}
+				}
+
+				func (f *{{$faultType}}) Unwrap() error {
+					return f.SoapFault
+				}
+
+				func (f *{{$faultType}}) SoapFaultDetail() (*gowsdl.SoapFault, interface{}) {
+					return f.SoapFault, f.FaultDetail
+				}
+
+				func new{{$faultType}}(fault *gowsdl.SoapFault) *{{$faultType}} {
+					{{with findFaultElement .Message}}if name := fault.DetailName(); name.Local != {{printf "%q" .Local}}{{if .Space}} || name.Space != {{printf "%q" .Space}}{{end}} {
+						return nil
+					}{{end}}
+					detail := &{{replaceStar $detailType}}{}
+					if err := fault.UnmarshalDetail(detail); err != nil {
+						return nil
+					}
+					return &{{$faultType}}{SoapFault: fault, FaultDetail: detail}
+				}
+			{{end}}
+		{{end}}
+
 		{{$faults := len .Faults}}
 		{{$requestType := findType .Input.Message }}
-		{{$soapAction := findSoapAction .Name $portType}}
+		{{$soapAction := findSoapAction .Name $portTypeName}}
+		{{with findAddressingAction . $portTypeName}}{{$soapAction = .}}{{end}}
 		{{$responseType := findType .Output.Message }}
+		{{$operation := makePublic .Name | replaceReservedWords}}
+		{{$parts := operationParts . $portTypeName}}
+		{{with $parts.Request}}
+			{{$requestType = printf "*%sRequest" $operation}}
+			{{template "PartsStruct" dictValues "Name" (printf "%sRequest" $operation) "Message" .}}
+		{{end}}
+		{{with $parts.Response}}
+			{{$responseType = printf "*%sResponse" $operation}}
+			{{template "PartsStruct" dictValues "Name" (printf "%sResponse" $operation) "Message" .}}
+		{{end}}
+
+		{{$headers := operationHeaders . $portTypeName}}
+		{{$requestHeader := ""}}
+		{{$responseHeader := ""}}
+		{{with $headers.Request}}
+			{{$requestHeader = printf "*%sRequestHeader" $operation}}
+			{{template "HeaderStruct" dictValues "Name" (printf "%sRequestHeader" $operation) "Message" (printf "%s request" $operation) "Header" .}}
+		{{end}}
+		{{with $headers.Response}}
+			{{$responseHeader = printf "*%sResponseHeader" $operation}}
+			{{template "HeaderStruct" dictValues "Name" (printf "%sResponseHeader" $operation) "Message" (printf "%s response" $operation) "Header" .}}
+		{{end}}
 
 		{{/*if ne $soapAction ""*/}}
 		{{if gt $faults 0}}
 		// Error can be either of the following types:
 		// {{range .Faults}}
-		//   - {{.Name}} {{.Doc}}{{end}}{{end}}
-		{{if ne .Doc ""}}/* {{.Doc}} */{{end}}
-		func (service *{{$portType}}) {{makePublic .Name | replaceReservedWords}} ({{if ne $requestType ""}}request {{$requestType}}{{end}}, header *gowsdl.SoapHeader, configureRequest func(*http.Request)) ({{$responseType}}, error) {
+		//   - {{.Name}} {{if findFaultType .Message}}(*{{faultTypeName .Message}}) {{end}}{{.Doc}}{{end}}{{end}}
+		{{if ne .Doc ""}}/* {{.Doc}} */{{end}}{{if ne $responseHeader ""}}
+		// The response header is also returned along with SOAP faults.{{end}}
+		func (service *{{$portType}}) {{$operation}} ({{if ne $requestType ""}}request {{$requestType}}, {{end}}{{if ne $requestHeader ""}}requestHeader {{$requestHeader}}, {{end}}header *gowsdl.SoapHeader, configureRequest func(*http.Request)) ({{$responseType}}, {{if ne $responseHeader ""}}{{$responseHeader}}, {{end}}error) {
+			return service.{{$operation}}Context(context.Background(), {{if ne $requestType ""}}request, {{end}}{{if ne $requestHeader ""}}requestHeader, {{end}}header, configureRequest)
+		}
+
+		// {{$operation}}Context is like {{$operation}} but the call is bound to ctx.
+		func (service *{{$portType}}) {{$operation}}Context (ctx context.Context, {{if ne $requestType ""}}request {{$requestType}}, {{end}}{{if ne $requestHeader ""}}requestHeader {{$requestHeader}}, {{end}}header *gowsdl.SoapHeader, configureRequest func(*http.Request)) ({{$responseType}}, {{if ne $responseHeader ""}}{{$responseHeader}}, {{end}}error) {
 			response := &{{replaceStar $responseType}}{}
-			err := service.client.Call("{{$soapAction}}", {{if ne $requestType ""}}request{{else}}nil{{end}}, response, header, configureRequest)
+			{{if or (ne $requestHeader "") (ne $responseHeader "")}}
+			if header == nil {
+				header = &gowsdl.SoapHeader{}
+			}
+			{{if ne $requestHeader ""}}header.ReqHeader = requestHeader{{end}}
+			{{if ne $responseHeader ""}}
+			responseHeader := &{{replaceStar $responseHeader}}{}
+			header.RespHeader = responseHeader
+			{{end}}
+			{{end}}
+			err := service.client.CallContext(ctx, "{{$soapAction}}", {{if ne $requestType ""}}request{{else}}nil{{end}}, response, header, configureRequest)
 			if err != nil {
-				return nil, err
+				{{if or $typedFaults (ne $responseHeader "")}}
+				if fault, ok := err.(*gowsdl.SoapFault); ok {
+					{{range $typedFaults}}
+					if typedFault := new{{faultTypeName .Message}}(fault); typedFault != nil {
+						return nil, {{if ne $responseHeader ""}}responseHeader, {{end}}typedFault
+					}
+					{{end}}
+					{{if ne $responseHeader ""}}return nil, responseHeader, fault{{end}}
+				}
+				{{end}}
+				return nil, {{if ne $responseHeader ""}}nil, {{end}}err
 			}
 
-			return response, nil
+			return response, {{if ne $responseHeader ""}}responseHeader, {{end}}nil
 		}
 		{{/*end*/}}
 	{{end}}
 {{end}}
+{{end}}
+{{define "PartsStruct"}}
+	{{$name := .Name}}
+	{{with .Message}}
+	// {{$name}} holds the parts of the {{.Message}} message.
+	type {{$name}} struct {
+		{{range .Parts}}{{.Field}} {{.GoType}}
+		{{end}}
+	}
+
+	func (r *{{$name}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+		{{if .RPC}}
+		return gowsdl.EncodeRPC(e, xml.Name{Space: {{printf "%q" .Namespace}}, Local: {{printf "%q" .Element}}}, {{printf "%q" .EncodingStyle}}, []gowsdl.RPCPart{
+			{{range .Parts}}{Name: {{printf "%q" .Name}}, Type: xml.Name{Space: {{printf "%q" .Type.Space}}, Local: {{printf "%q" .Type.Local}}}, {{if .ItemType.Local}}ItemType: xml.Name{Space: {{printf "%q" .ItemType.Space}}, Local: {{printf "%q" .ItemType.Local}}}, {{end}}Value: r.{{.Field}}},
+			{{end}}
+		})
+		{{else}}
+		return gowsdl.EncodeParts(e, []gowsdl.Part{
+			{{range .Parts}}{Name: xml.Name{Space: {{printf "%q" .Element.Space}}, Local: {{printf "%q" .Element.Local}}}, Value: r.{{.Field}}},
+			{{end}}
+		})
+		{{end}}
+	}
+	{{if .EncodingStyle}}
+	func (r *{{$name}}) SoapEncodingStyle() string {
+		return {{printf "%q" .EncodingStyle}}
+	}
+	{{end}}
+	{{if .RPC}}
+	func (r *{{$name}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+		return gowsdl.DecodeRPC(d, start, map[string]interface{}{
+			{{range .Parts}}{{printf "%q" .Name}}: &r.{{.Field}},
+			{{end}}
+		})
+	}
+	{{else}}
+	func (r *{{$name}}) UnmarshalParts(content string) error {
+		return gowsdl.DecodeParts(content, []gowsdl.Part{
+			{{range .Parts}}{Name: xml.Name{Space: {{printf "%q" .Element.Space}}, Local: {{printf "%q" .Element.Local}}}, Value: &r.{{.Field}}},
+			{{end}}
+		})
+	}
+	{{end}}
+	{{end}}
+{{end}}
+
+{{define "HeaderStruct"}}
+	{{$name := .Name}}
+	{{$message := .Message}}
+	{{with .Header}}
+	// {{$name}} holds the header blocks of the {{$message}}.{{if .Faults}}
+	// The header faults are set when the service answers with a fault.{{end}}
+	type {{$name}} struct {
+		{{range .Parts}}{{.Field}} {{.GoType}}
+		{{end}}{{if .Faults}}
+		// Header faults
+		{{range .Faults}}{{.Field}} {{.GoType}}
+		{{end}}{{end}}
+	}
+
+	func (h *{{$name}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+		return gowsdl.EncodeParts(e, []gowsdl.Part{
+			{{range .AllParts}}{Name: xml.Name{Space: {{printf "%q" .Element.Space}}, Local: {{printf "%q" .Element.Local}}}, Value: h.{{.Field}}},
+			{{end}}
+		})
+	}
+
+	func (h *{{$name}}) UnmarshalParts(content string) error {
+		return gowsdl.DecodeParts(content, []gowsdl.Part{
+			{{range .AllParts}}{Name: xml.Name{Space: {{printf "%q" .Element.Space}}, Local: {{printf "%q" .Element.Local}}}, Value: &h.{{.Field}}},
+			{{end}}
+		})
+	}
+	{{end}}
+{{end}}
 `
@@ -0,0 +1,124 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Part is a message part sent as the element Name, whatever the XMLName of
+// the type of its value. Nil values are not sent.
+type Part struct {
+	Name  xml.Name
+	Value interface{}
+}
+
+func (p Part) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if isNilValue(p.Value) {
+		return nil
+	}
+	return e.EncodeElement(p.Value, xml.StartElement{Name: p.Name})
+}
+
+// SoapElement is implemented by the generated types which are the type of
+// the element of the same name. They have no XMLName, so that fields of their
+// type are named after the field, and are sent as the element SoapElementName
+// otherwise.
+type SoapElement interface {
+	SoapElementName() xml.Name
+}
+
+// Returns value as the element it is sent as, a Part named after its element
+// for SoapElement values.
+func namedElement(value interface{}) interface{} {
+	if element, ok := value.(SoapElement); ok {
+		return Part{Name: element.SoapElementName(), Value: value}
+	}
+	return value
+}
+
+// PartsUnmarshaler is implemented by the responses and response headers of
+// generated operations which aren't a single element, ie. messages with
+// several body parts. They are decoded from the raw content of the response
+// body or header.
+type PartsUnmarshaler interface {
+	UnmarshalParts(content string) error
+}
+
+// SoapEncodedMessage is implemented by the requests and responses of
+// generated operations bound with use="encoded", whose multi-reference
+// values are inlined before they are decoded.
+type SoapEncodedMessage interface {
+	SoapEncodingStyle() string
+}
+
+// UnmarshalContent decodes the raw content of a body or a header into v,
+// either a PartsUnmarshaler or the type of its single element.
+func UnmarshalContent(content string, v interface{}) error {
+	if _, ok := v.(SoapEncodedMessage); ok {
+		var err error
+		if content, err = resolveMultiRefs(content); err != nil {
+			return err
+		}
+	}
+	if u, ok := v.(PartsUnmarshaler); ok {
+		return u.UnmarshalParts(content)
+	}
+	return xml.Unmarshal([]byte(content), v)
+}
+
+// EncodeParts writes the parts of a message one after the other.
+func EncodeParts(e *xml.Encoder, parts []Part) error {
+	for _, part := range parts {
+		if err := part.MarshalXML(e, xml.StartElement{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecodeParts decodes the top level elements of content into parts, whose
+// values are pointers to the fields holding them. Elements are matched by
+// local name with the first part not decoded yet, unknown elements are
+// skipped.
+func DecodeParts(content string, parts []Part) error {
+	decoded := make([]bool, len(parts))
+
+	d := xml.NewDecoder(strings.NewReader(content))
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		i := 0
+		for ; i < len(parts); i++ {
+			if !decoded[i] && parts[i].Name.Local == start.Name.Local {
+				break
+			}
+		}
+		if i == len(parts) {
+			if err := d.Skip(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		decoded[i] = true
+		if err := decodeRPCValue(d, start, reflect.ValueOf(parts[i].Value).Elem()); err != nil {
+			return err
+		}
+	}
+}
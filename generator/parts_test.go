@@ -0,0 +1,141 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type bankAuth struct {
+	XMLName xml.Name `xml:"http://example.com/bank Auth"`
+	Token   string   `xml:"Token"`
+}
+
+type transferRequest struct {
+	Source string
+	Amount float64
+}
+
+func (r *transferRequest) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return EncodeParts(e, []Part{
+		{Name: xml.Name{Space: "http://example.com/bank", Local: "Source"}, Value: r.Source},
+		{Name: xml.Name{Space: "http://example.com/bank", Local: "Amount"}, Value: r.Amount},
+	})
+}
+
+type transferRequestHeader struct {
+	Auth *bankAuth
+}
+
+func (h *transferRequestHeader) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return EncodeParts(e, []Part{
+		{Name: xml.Name{Space: "http://example.com/bank", Local: "Auth"}, Value: h.Auth},
+	})
+}
+
+type transferResponse struct {
+	Receipt string
+	Balance float64
+}
+
+func (r *transferResponse) UnmarshalParts(content string) error {
+	return DecodeParts(content, []Part{
+		{Name: xml.Name{Space: "http://example.com/bank", Local: "Receipt"}, Value: &r.Receipt},
+		{Name: xml.Name{Space: "http://example.com/bank", Local: "Balance"}, Value: &r.Balance},
+	})
+}
+
+type transferResponseHeader struct {
+	Session string
+	Fault   *struct {
+		Reason string
+	}
+}
+
+func (h *transferResponseHeader) UnmarshalParts(content string) error {
+	return DecodeParts(content, []Part{
+		{Name: xml.Name{Space: "http://example.com/bank", Local: "Session"}, Value: &h.Session},
+		{Name: xml.Name{Space: "http://example.com/bank", Local: "AuthFault"}, Value: &h.Fault},
+	})
+}
+
+const transferResponseEnvelope = `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:b="http://example.com/bank">
+	<soap:Header><b:Session>s1</b:Session></soap:Header>
+	<soap:Body><b:Receipt>r1</b:Receipt><b:Balance>12.5</b:Balance></soap:Body>
+</soap:Envelope>`
+
+const transferFaultEnvelope = `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:b="http://example.com/bank">
+	<soap:Header><b:AuthFault><b:Reason>expired</b:Reason></b:AuthFault></soap:Header>
+	<soap:Body><soap:Fault><faultcode>soap:Client</faultcode><faultstring>unauthorized</faultstring></soap:Fault></soap:Body>
+</soap:Envelope>`
+
+func TestSoapCallParts(t *testing.T) {
+	var request []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte(transferResponseEnvelope))
+	}))
+	defer ts.Close()
+
+	client := NewSoapClient(ts.URL, false)
+	response := &transferResponse{}
+	responseHeader := &transferResponseHeader{}
+	header := &SoapHeader{
+		ReqHeader:  &transferRequestHeader{Auth: &bankAuth{Token: "t"}},
+		RespHeader: responseHeader,
+	}
+	err := client.Call("", &transferRequest{Source: "NL01", Amount: 2}, response, header, nil)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	envelope := struct {
+		Header struct {
+			Auth bankAuth
+		}
+		Body struct {
+			Source string  `xml:"http://example.com/bank Source"`
+			Amount float64 `xml:"http://example.com/bank Amount"`
+		}
+	}{}
+	if err := xml.Unmarshal(request, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Header.Auth.Token != "t" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", envelope.Header.Auth.Token, "t")
+	}
+	if envelope.Body.Source != "NL01" || envelope.Body.Amount != 2 {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", envelope.Body, "NL01, 2")
+	}
+
+	want := &transferResponse{Receipt: "r1", Balance: 12.5}
+	if *response != *want {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", response, want)
+	}
+	if responseHeader.Session != "s1" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", responseHeader.Session, "s1")
+	}
+}
+
+func TestSoapCallHeaderFault(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(transferFaultEnvelope))
+	}))
+	defer ts.Close()
+
+	client := NewSoapClient(ts.URL, false)
+	responseHeader := &transferResponseHeader{}
+	err := client.Call("", &transferRequest{}, &transferResponse{}, &SoapHeader{RespHeader: responseHeader}, nil)
+	if _, ok := err.(*SoapFault); !ok {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, &SoapFault{})
+	}
+	if responseHeader.Fault == nil || responseHeader.Fault.Reason != "expired" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", responseHeader.Fault, "expired")
+	}
+}
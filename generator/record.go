@@ -0,0 +1,216 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Record and replay of SOAP calls. Every exchange is saved as two HTTP
+// messages, <key>.request and <key>.response, as dumped by net/http/httputil
+// so that they can be read and edited by hand. The key is made of the SOAP
+// action and of a hash of the normalised content of the request body, which
+// leaves out the envelope header and its per call values such as message ids,
+// nonces and signatures.
+
+type recorder struct {
+	dir  string
+	next http.RoundTripper
+}
+
+// NewRecorder returns a transport sending the requests through next, or
+// http.DefaultTransport if nil, and saving every request along with its
+// response to dir, to be replayed later by NewReplayer.
+func NewRecorder(dir string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &recorder{dir: dir, next: next}
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	req, body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	key, err := recordKey(req, body)
+	if err != nil {
+		return nil, err
+	}
+
+	dump, err := httputil.DumpRequestOut(req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	// Saves the body with its length, whatever the transfer encoding.
+	resBody, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(resBody))
+	res.ContentLength = int64(len(resBody))
+	res.TransferEncoding = nil
+
+	resDump, err := httputil.DumpResponse(res, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(r.dir, key)
+	if err := ioutil.WriteFile(path+".request", dump, 0644); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path+".response", resDump, 0644); err != nil {
+		return nil, err
+	}
+	Log.Debug("Recorded", "file", path)
+
+	return res, nil
+}
+
+type replayer struct {
+	dir string
+}
+
+// NewReplayer returns a transport answering the requests with the responses
+// recorded to dir by NewRecorder, without any network access. Requests that
+// were not recorded fail.
+func NewReplayer(dir string) http.RoundTripper {
+	return &replayer{dir: dir}
+}
+
+func (r *replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	req, body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	key, err := recordKey(req, body)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(r.dir, key+".response")
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no recorded response for action %q: %s does not exist", requestAction(req), path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	Log.Debug("Replayed", "file", path)
+
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+}
+
+// Reads the body of a request, returning a copy of the request whose body
+// can still be sent.
+func readRequestBody(req *http.Request) (*http.Request, []byte, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	return req, body, nil
+}
+
+// Returns the SOAP action of a request, either its SOAPAction header or the
+// action parameter of its SOAP 1.2 media type.
+func requestAction(req *http.Request) string {
+	if action := strings.Trim(req.Header.Get("SOAPAction"), `"`); action != "" {
+		return action
+	}
+	_, params, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return params["action"]
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Returns the file name, without extension, of the record of a request.
+func recordKey(req *http.Request, body []byte) (string, error) {
+	content, err := normalizedBody(req.Header.Get("Content-Type"), body)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:8])
+
+	action := strings.Trim(unsafeFileChars.ReplaceAllString(requestAction(req), "_"), "_")
+	if action == "" {
+		return hash, nil
+	}
+	return action + "-" + hash, nil
+}
+
+// Returns the canonical form of the content of the body of an envelope,
+// whitespace between elements aside. MTOM messages are normalised once their
+// attachments are inlined.
+func normalizedBody(contentType string, data []byte) ([]byte, error) {
+	data, _, err := unpackEnvelope(contentType, data)
+	if err != nil {
+		return nil, err
+	}
+	envelope, err := parseXMLElement(data)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := &bytes.Buffer{}
+	for _, child := range envelope.Children {
+		body, ok := child.(*xmlElement)
+		if !ok || body.Local != "Body" {
+			continue
+		}
+		for _, c := range body.Children {
+			if el, ok := c.(*xmlElement); ok {
+				trimWhitespace(el)
+				buffer.Write(el.canonicalize(nil))
+			}
+		}
+	}
+	return buffer.Bytes(), nil
+}
+
+// Drops the whitespace only text around child elements, ie. indentation.
+func trimWhitespace(e *xmlElement) {
+	var children []interface{}
+	for _, child := range e.Children {
+		if text, ok := child.(xmlText); ok && len(e.Children) > 1 && strings.TrimSpace(string(text)) == "" {
+			continue
+		}
+		if el, ok := child.(*xmlElement); ok {
+			trimWhitespace(el)
+		}
+		children = append(children, child)
+	}
+	e.Children = children
+}
@@ -0,0 +1,53 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gowsdl-record")
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	defer os.RemoveAll(dir)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(pongEnvelope))
+	}))
+
+	// WS-Addressing sends a new message id with every call, which must not
+	// change the record of the call.
+	client := NewSoapClient(ts.URL, false, WithWSAddressing(), WithRecording(dir))
+	if err := client.Call("urn:ping", &pingRequest{Message: "ping"}, &pingResponse{}, nil, nil); err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	ts.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 2 || !strings.HasPrefix(filepath.Base(files[0]), "urn_ping-") {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", files, "urn_ping-<hash>.request and .response")
+	}
+
+	client = NewSoapClient(ts.URL, false, WithWSAddressing(), WithReplay(dir))
+	response := &pingResponse{}
+	if err := client.Call("urn:ping", &pingRequest{Message: "ping"}, response, nil, nil); err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	if response.Message != "pong" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", response.Message, "pong")
+	}
+
+	err = client.Call("urn:ping", &pingRequest{Message: "other"}, &pingResponse{}, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", err, "no recorded response error")
+	}
+}
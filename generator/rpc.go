@@ -0,0 +1,331 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	XsdNamespace            = "http://www.w3.org/2001/XMLSchema"
+	XsiNamespace            = "http://www.w3.org/2001/XMLSchema-instance"
+	SoapEncodingNamespace   = "http://schemas.xmlsoap.org/soap/encoding/"
+	Soap12EncodingNamespace = "http://www.w3.org/2003/05/soap-encoding"
+)
+
+// RPCPart is a part of an RPC style message, sent as an accessor element
+// named after the part. Type and ItemType, the type of the elements of SOAP
+// encoded arrays, are only used by the SOAP encoding.
+type RPCPart struct {
+	Name     string
+	Type     xml.Name
+	ItemType xml.Name
+	Value    interface{}
+}
+
+// Prefixes declared on an RPC wrapper element for the xsi:type values.
+type rpcPrefixes struct {
+	names map[string]string
+	attrs []xml.Attr
+}
+
+func (p *rpcPrefixes) qname(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+
+	prefix, ok := p.names[name.Space]
+	if !ok {
+		switch name.Space {
+		case XsdNamespace:
+			prefix = "xsd"
+		case SoapEncodingNamespace, Soap12EncodingNamespace:
+			prefix = "soapenc"
+		default:
+			prefix = "ns" + strconv.Itoa(len(p.names)+1)
+		}
+		p.names[name.Space] = prefix
+		p.attrs = append(p.attrs, xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: name.Space})
+	}
+	return prefix + ":" + name.Local
+}
+
+// EncodeRPC writes the RPC style wrapper element name of a message, with one
+// unqualified accessor element per part. encodingStyle is the SOAP encoding
+// of the parts, "" for literal parts. SOAP encoded parts are annotated with
+// their xsi:type and slices are encoded as SOAP encoded arrays.
+func EncodeRPC(e *xml.Encoder, name xml.Name, encodingStyle string, parts []RPCPart) error {
+	start := xml.StartElement{
+		Name: xml.Name{Local: "rpc:" + name.Local},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns:rpc"}, Value: name.Space}},
+	}
+
+	encoded := encodingStyle != ""
+	soap12 := encodingStyle == Soap12EncodingNamespace
+	prefixes := &rpcPrefixes{names: map[string]string{}}
+
+	// Encodes the parts first so that every prefix they use is known when
+	// writing the wrapper element.
+	buffer := &bytes.Buffer{}
+	partEncoder := xml.NewEncoder(buffer)
+	if attachments, ok := mtomEncoders.Load(e); ok {
+		mtomEncoders.Store(partEncoder, attachments)
+		defer mtomEncoders.Delete(partEncoder)
+	}
+	for _, part := range parts {
+		var err error
+		if encoded {
+			err = encodeRPCValue(partEncoder, part.Name, part.Type, part.ItemType, part.Value, soap12, prefixes)
+		} else if !isNilValue(part.Value) {
+			err = partEncoder.EncodeElement(part.Value, xml.StartElement{Name: xml.Name{Local: part.Name}})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := partEncoder.Flush(); err != nil {
+		return err
+	}
+
+	if encoded {
+		envelopeNamespace := soapEnvelopeNamespace
+		if soap12 {
+			envelopeNamespace = soap12EnvelopeNamespace
+		}
+		start.Attr = append(start.Attr,
+			xml.Attr{Name: xml.Name{Local: "xmlns:xsi"}, Value: XsiNamespace},
+			xml.Attr{Name: xml.Name{Space: envelopeNamespace, Local: "encodingStyle"}, Value: encodingStyle})
+		start.Attr = append(start.Attr, prefixes.attrs...)
+	}
+
+	return e.EncodeElement(struct {
+		Parts string `xml:",innerxml"`
+	}{buffer.String()}, start)
+}
+
+func encodeRPCValue(e *xml.Encoder, name string, xsiType, itemType xml.Name, value interface{}, soap12 bool, prefixes *rpcPrefixes) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+
+	if isNilValue(value) {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xsi:nil"}, Value: "true"})
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		return e.EncodeToken(start.End())
+	}
+
+	v := reflect.ValueOf(value)
+	if !isArrayValue(v) {
+		if xsiType.Local != "" {
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xsi:type"}, Value: prefixes.qname(xsiType)})
+		}
+		return e.EncodeElement(value, start)
+	}
+
+	encoding := SoapEncodingNamespace
+	if soap12 {
+		encoding = Soap12EncodingNamespace
+	}
+	arrayType := prefixes.qname(xml.Name{Space: encoding, Local: "Array"})
+	start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xsi:type"}, Value: arrayType})
+	if soap12 {
+		start.Attr = append(start.Attr,
+			xml.Attr{Name: xml.Name{Local: prefixes.qname(xml.Name{Space: encoding, Local: "itemType"})}, Value: prefixes.qname(itemType)},
+			xml.Attr{Name: xml.Name{Local: prefixes.qname(xml.Name{Space: encoding, Local: "arraySize"})}, Value: strconv.Itoa(v.Len())})
+	} else {
+		start.Attr = append(start.Attr,
+			xml.Attr{Name: xml.Name{Local: prefixes.qname(xml.Name{Space: encoding, Local: "arrayType"})}, Value: fmt.Sprintf("%s[%d]", prefixes.qname(itemType), v.Len())})
+	}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for i := 0; i < v.Len(); i++ {
+		if err := encodeRPCValue(e, "item", itemType, xml.Name{}, v.Index(i).Interface(), soap12, prefixes); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// DecodeRPC decodes the accessor elements of an RPC style wrapper element
+// into parts, pointers to the fields of the parts keyed by part name. Slices
+// are decoded from SOAP encoded arrays and unknown accessors are skipped.
+func DecodeRPC(d *xml.Decoder, start xml.StartElement, parts map[string]interface{}) error {
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			v, ok := parts[t.Name.Local]
+			if !ok {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := decodeRPCValue(d, t, reflect.ValueOf(v).Elem()); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+func decodeRPCValue(d *xml.Decoder, start xml.StartElement, v reflect.Value) error {
+	for _, attr := range start.Attr {
+		if attr.Name.Space == XsiNamespace && attr.Name.Local == "nil" && attr.Value == "true" {
+			return d.Skip()
+		}
+	}
+
+	if !isArrayValue(v) {
+		// The accessor is named after the part, not after the type.
+		if name, ok := xmlNameOf(v.Type()); ok {
+			start.Name = name
+		}
+		return d.DecodeElement(v.Addr().Interface(), &start)
+	}
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			item := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeRPCValue(d, t, item); err != nil {
+				return err
+			}
+			v.Set(reflect.Append(v, item))
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// Whether v is sent as an array, byte slices being base64 encoded.
+func isArrayValue(v reflect.Value) bool {
+	return v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8
+}
+
+func isNilValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// Returns the name of the XMLName field tag of a struct type, if any.
+func xmlNameOf(t reflect.Type) (xml.Name, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return xml.Name{}, false
+	}
+
+	field, ok := t.FieldByName("XMLName")
+	if !ok || field.Type != reflect.TypeOf(xml.Name{}) {
+		return xml.Name{}, false
+	}
+	tag := strings.Split(field.Tag.Get("xml"), ",")[0]
+	if tag == "" {
+		return xml.Name{}, false
+	}
+
+	if i := strings.LastIndex(tag, " "); i >= 0 {
+		return xml.Name{Space: tag[:i], Local: tag[i+1:]}, true
+	}
+	return xml.Name{Local: tag}, true
+}
+
+// Inlines the multi-reference values of a SOAP encoded body: the elements
+// with an href="#id" attribute get the attributes and content of the element
+// with the matching id, and the top level referenced elements are dropped.
+func resolveMultiRefs(content string) (string, error) {
+	root, err := parseXMLElement([]byte("<multiRefs>" + content + "</multiRefs>"))
+	if err != nil {
+		return "", err
+	}
+
+	ids := map[string]*xmlElement{}
+	root.walk(func(el *xmlElement) {
+		if id, ok := el.attr("", "id"); ok {
+			ids[id] = el
+		}
+	})
+
+	resolving := map[*xmlElement]bool{}
+	var resolve func(el *xmlElement) error
+	resolve = func(el *xmlElement) error {
+		if resolving[el] {
+			return fmt.Errorf("circular multi-reference in %s", el.qname())
+		}
+		resolving[el] = true
+		defer delete(resolving, el)
+
+		if href, ok := el.attr("", "href"); ok && strings.HasPrefix(href, "#") {
+			ref := ids[href[1:]]
+			if ref == nil {
+				return fmt.Errorf("unresolved multi-reference %q", href)
+			}
+			if err := resolve(ref); err != nil {
+				return err
+			}
+
+			var attrs []xml.Attr
+			for _, attr := range el.Attrs {
+				if attr.Name.Space != "" || attr.Name.Local != "href" {
+					attrs = append(attrs, attr)
+				}
+			}
+			for _, attr := range ref.Attrs {
+				if attr.Name.Space != "" || attr.Name.Local != "id" {
+					attrs = append(attrs, attr)
+				}
+			}
+			el.Attrs = attrs
+			el.Children = ref.Children
+			return nil
+		}
+
+		for _, child := range el.Children {
+			if c, ok := child.(*xmlElement); ok {
+				if err := resolve(c); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	buffer := &bytes.Buffer{}
+	for _, child := range root.Children {
+		el, ok := child.(*xmlElement)
+		if !ok {
+			continue
+		}
+		if _, referenced := el.attr("", "id"); referenced {
+			continue
+		}
+		if err := resolve(el); err != nil {
+			return "", err
+		}
+		el.writeTo(buffer)
+	}
+	return buffer.String(), nil
+}
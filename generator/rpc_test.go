@@ -0,0 +1,157 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type rpcItem struct {
+	XMLName  xml.Name `xml:"urn:inventory Item"`
+	Sku      string   `xml:"Sku"`
+	Quantity int32    `xml:"Quantity"`
+}
+
+type rpcRequest struct {
+	Sku   string
+	Skus  []string
+	Limit *int32
+}
+
+func (r *rpcRequest) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return EncodeRPC(e, xml.Name{Space: "urn:inventory", Local: "getItems"}, SoapEncodingNamespace, []RPCPart{
+		{Name: "sku", Type: xml.Name{Space: XsdNamespace, Local: "string"}, Value: r.Sku},
+		{Name: "skus", Type: xml.Name{Space: SoapEncodingNamespace, Local: "Array"}, ItemType: xml.Name{Space: XsdNamespace, Local: "string"}, Value: r.Skus},
+		{Name: "limit", Type: xml.Name{Space: XsdNamespace, Local: "int"}, Value: r.Limit},
+	})
+}
+
+type rpcResponse struct {
+	Items []*rpcItem
+	Count int32
+	Limit *int32
+}
+
+func (r *rpcResponse) SoapEncodingStyle() string {
+	return SoapEncodingNamespace
+}
+
+func (r *rpcResponse) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return DecodeRPC(d, start, map[string]interface{}{
+		"items": &r.Items,
+		"count": &r.Count,
+		"limit": &r.Limit,
+	})
+}
+
+// Axis 1 style response, with multi-reference values and the xsi prefix
+// declared by the Envelope only.
+const rpcResponseEnvelope = `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:soapenc="http://schemas.xmlsoap.org/soap/encoding/">
+	<soapenv:Body>
+		<ns1:getItemsResponse soapenv:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/" xmlns:ns1="urn:inventory">
+			<items xsi:type="soapenc:Array" soapenc:arrayType="ns1:Item[2]">
+				<item href="#id0"/>
+				<item href="#id1"/>
+			</items>
+			<count xsi:type="xsd:int" xmlns:xsd="http://www.w3.org/2001/XMLSchema">2</count>
+			<limit xsi:nil="true"/>
+		</ns1:getItemsResponse>
+		<multiRef id="id0" soapenc:root="0" xsi:type="ns2:Item" xmlns:ns2="urn:inventory"><Sku>a</Sku><Quantity>1</Quantity></multiRef>
+		<multiRef id="id1" soapenc:root="0" xsi:type="ns3:Item" xmlns:ns3="urn:inventory"><Sku>b</Sku><Quantity xsi:nil="true"/></multiRef>
+	</soapenv:Body>
+</soapenv:Envelope>`
+
+func TestEncodeRPC(t *testing.T) {
+	data, err := xml.Marshal(&rpcRequest{Sku: "a&b", Skus: []string{"c", "d"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(data)
+	want := `<rpc:getItems xmlns:rpc="urn:inventory" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:envelope="http://schemas.xmlsoap.org/soap/envelope/" envelope:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/" xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:soapenc="http://schemas.xmlsoap.org/soap/encoding/">` +
+		`<sku xsi:type="xsd:string">a&amp;b</sku>` +
+		`<skus xsi:type="soapenc:Array" soapenc:arrayType="xsd:string[2]"><item xsi:type="xsd:string">c</item><item xsi:type="xsd:string">d</item></skus>` +
+		`<limit xsi:nil="true"></limit>` +
+		`</rpc:getItems>`
+	if got != want {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestSoapCallRPC(t *testing.T) {
+	var request []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte(rpcResponseEnvelope))
+	}))
+	defer ts.Close()
+
+	client := NewSoapClient(ts.URL, false)
+	response := &rpcResponse{}
+	err := client.Call("", &rpcRequest{Sku: "a"}, response, nil, nil)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	envelope := struct {
+		Body struct {
+			Request struct {
+				XMLName xml.Name
+				Sku     string `xml:"sku"`
+			} `xml:",any"`
+		}
+	}{}
+	if err := xml.Unmarshal(request, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := envelope.Body.Request.XMLName, (xml.Name{Space: "urn:inventory", Local: "getItems"}); got != want {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", got, want)
+	}
+	if envelope.Body.Request.Sku != "a" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", envelope.Body.Request.Sku, "a")
+	}
+
+	want := &rpcResponse{
+		Items: []*rpcItem{
+			{XMLName: xml.Name{Space: "urn:inventory", Local: "Item"}, Sku: "a", Quantity: 1},
+			{XMLName: xml.Name{Space: "urn:inventory", Local: "Item"}, Sku: "b"},
+		},
+		Count: 2,
+	}
+	if !reflect.DeepEqual(response, want) {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", response, want)
+	}
+}
+
+func TestSoapCallLiteralIds(t *testing.T) {
+	// A literal response with an id and an href which aren't multi-references.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+			<soap:Body><Link xmlns="urn:inventory" id="item-1" href="#top">Item</Link></soap:Body>
+		</soap:Envelope>`))
+	}))
+	defer ts.Close()
+
+	type link struct {
+		XMLName xml.Name `xml:"urn:inventory Link"`
+		Id      string   `xml:"id,attr"`
+		Href    string   `xml:"href,attr"`
+		Text    string   `xml:",chardata"`
+	}
+	client := NewSoapClient(ts.URL, false)
+	response := &link{}
+	if err := client.Call("", &pingRequest{}, response, nil, nil); err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	want := &link{XMLName: xml.Name{Space: "urn:inventory", Local: "Link"}, Id: "item-1", Href: "#top", Text: "Item"}
+	if !reflect.DeepEqual(response, want) {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", response, want)
+	}
+}
@@ -0,0 +1,321 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// SoapOperation is an operation served by a SOAP handler. Requests are
+// dispatched on their SOAP action or, when no operation has it, on the name
+// of the root element of their body.
+type SoapOperation struct {
+	Name    string
+	Action  string
+	Element xml.Name
+	// Serve decodes the raw content of the header and the body of a request,
+	// whose elements redeclare the namespaces declared by the envelope,
+	// invokes the service and returns its response, response header (which
+	// may be nil) or error. *SoapFault and DetailedFault errors are sent as
+	// they are, other errors as server faults.
+	Serve func(ctx context.Context, header, body string) (response, responseHeader interface{}, err error)
+}
+
+// DetailedFault is implemented by the typed faults of the generated
+// operations, their detail is sent in the detail of the fault.
+type DetailedFault interface {
+	error
+	SoapFaultDetail() (*SoapFault, interface{})
+}
+
+// NewClientFault returns the client fault sent for a request which can't be
+// decoded.
+func NewClientFault(err error) *SoapFault {
+	return &SoapFault{Faultcode: "Client", Faultstring: err.Error()}
+}
+
+type soapHandler struct {
+	soap12     bool
+	operations []*SoapOperation
+}
+
+// NewSoapHandler returns an http.Handler serving SOAP 1.1 requests with the
+// given operations, ie. the ones of a generated port type server.
+func NewSoapHandler(operations []*SoapOperation) http.Handler {
+	return &soapHandler{operations: operations}
+}
+
+// NewSoap12Handler is like NewSoapHandler but serves SOAP 1.2 requests.
+func NewSoap12Handler(operations []*SoapOperation) http.Handler {
+	return &soapHandler{soap12: true, operations: operations}
+}
+
+func (h *soapHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "SOAP requests must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		h.writeFault(w, err)
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	data, _, err = unpackEnvelope(contentType, data)
+	if err != nil {
+		h.writeFault(w, NewClientFault(err))
+		return
+	}
+	header, body, _, err := parseEnvelope(data, h.soap12)
+	if err != nil {
+		h.writeFault(w, NewClientFault(err))
+		return
+	}
+
+	// SOAP 1.2 sends the action as a parameter of the media type.
+	action := strings.Trim(r.Header.Get("SOAPAction"), `"`)
+	if h.soap12 {
+		_, params, _ := mime.ParseMediaType(contentType)
+		action = params["action"]
+	}
+
+	operation := h.operation(action, body)
+	if operation == nil {
+		h.writeFault(w, NewClientFault(fmt.Errorf("no operation for action %q", action)))
+		return
+	}
+
+	headerContent := ""
+	if header != nil {
+		headerContent = header.Content
+	}
+	response, responseHeader, err := operation.Serve(r.Context(), headerContent, body)
+	if err != nil {
+		h.writeFault(w, err)
+		return
+	}
+
+	content, _, err := marshalRequest(response, false)
+	if err != nil {
+		h.writeFault(w, err)
+		return
+	}
+
+	var envelopeHeader *SoapHeader
+	if !isNilValue(responseHeader) {
+		envelopeHeader = &SoapHeader{Headers: []interface{}{namedElement(responseHeader)}}
+	}
+	h.writeEnvelope(w, http.StatusOK, newEnvelope(envelopeHeader, content, h.soap12))
+}
+
+// Returns the operation of a request, found by SOAP action or else by the
+// root element of its body.
+func (h *soapHandler) operation(action, body string) *SoapOperation {
+	if action != "" {
+		for _, op := range h.operations {
+			if op.Action == action {
+				return op
+			}
+		}
+	}
+
+	d := xml.NewDecoder(strings.NewReader(body))
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		for _, op := range h.operations {
+			if op.Element.Local == start.Name.Local &&
+				(op.Element.Space == "" || op.Element.Space == start.Name.Space) {
+				return op
+			}
+		}
+		return nil
+	}
+}
+
+func (h *soapHandler) writeEnvelope(w http.ResponseWriter, status int, envelope interface{}) {
+	buffer := &bytes.Buffer{}
+	if err := xml.NewEncoder(buffer).Encode(envelope); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if h.soap12 {
+		w.Header().Set("Content-Type", `application/soap+xml; charset="utf-8"`)
+	} else {
+		w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	}
+	w.WriteHeader(status)
+	w.Write(buffer.Bytes())
+}
+
+// Sends err as a fault, with the detail of DetailedFault errors. Faults
+// without a code are server faults.
+func (h *soapHandler) writeFault(w http.ResponseWriter, err error) {
+	fault, ok := err.(*SoapFault)
+	if detailed, isDetailed := err.(DetailedFault); isDetailed {
+		var detail interface{}
+		fault, detail = detailed.SoapFaultDetail()
+		if fault == nil {
+			fault = &SoapFault{Faultstring: "fault"}
+		}
+
+		if !isNilValue(detail) {
+			content, _, err := marshalRequest(detail, false)
+			if err != nil {
+				h.writeFault(w, err)
+				return
+			}
+			copied := *fault
+			copied.Detail = content
+			fault = &copied
+		}
+	} else if !ok {
+		fault = &SoapFault{Faultstring: err.Error()}
+	}
+
+	content, err := marshalFault(fault, h.soap12)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.writeEnvelope(w, http.StatusInternalServerError, newEnvelope(nil, content, h.soap12))
+}
+
+type faultDetail struct {
+	Content string `xml:",innerxml"`
+}
+
+type soapFault11 struct {
+	XMLName     xml.Name     `xml:"soap:Fault"`
+	Xmlns       string       `xml:"xmlns:soap,attr"`
+	Faultcode   string       `xml:"faultcode"`
+	Faultstring string       `xml:"faultstring"`
+	Faultactor  string       `xml:"faultactor,omitempty"`
+	Detail      *faultDetail `xml:"detail"`
+}
+
+type soapFault12 struct {
+	XMLName xml.Name            `xml:"soap:Fault"`
+	Xmlns   string              `xml:"xmlns:soap,attr"`
+	Code    *soapFault12Code    `xml:"soap:Code"`
+	Reasons []soapFault12Reason `xml:"soap:Reason>soap:Text"`
+	Node    string              `xml:"soap:Node,omitempty"`
+	Role    string              `xml:"soap:Role,omitempty"`
+	Detail  *faultDetail        `xml:"soap:Detail"`
+}
+
+type soapFault12Code struct {
+	Value   string           `xml:"soap:Value"`
+	Subcode *soapFault12Code `xml:"soap:Subcode"`
+}
+
+type soapFault12Reason struct {
+	Lang string `xml:"xml:lang,attr"`
+	Text string `xml:",chardata"`
+}
+
+// Marshals a fault in the shape of the given SOAP version. Unqualified fault
+// codes are qualified with the envelope namespace, SOAP 1.1 and 1.2 codes
+// being translated into each other, ie. Server into Receiver.
+func marshalFault(fault *SoapFault, soap12 bool) (string, error) {
+	var detail *faultDetail
+	if strings.TrimSpace(fault.Detail) != "" {
+		detail = &faultDetail{Content: fault.Detail}
+	}
+
+	var v interface{}
+	if soap12 {
+		code := fault.Code
+		if code == nil {
+			code = &SoapFaultCode{Value: fault.Faultcode}
+		}
+		reasons := fault.Reasons
+		if len(reasons) == 0 {
+			reasons = []SoapFaultReason{{Lang: "en", Text: fault.Faultstring}}
+		}
+
+		f := &soapFault12{
+			Xmlns:  soap12EnvelopeNamespace,
+			Code:   newSoapFault12Code(code),
+			Node:   fault.Node,
+			Role:   fault.Role,
+			Detail: detail,
+		}
+		f.Code.Value = faultCode(f.Code.Value, true)
+		for _, reason := range reasons {
+			f.Reasons = append(f.Reasons, soapFault12Reason{Lang: reason.Lang, Text: reason.Text})
+		}
+		v = f
+	} else {
+		v = &soapFault11{
+			Xmlns:       soapEnvelopeNamespace,
+			Faultcode:   faultCode(fault.Faultcode, false),
+			Faultstring: fault.Faultstring,
+			Faultactor:  fault.Faultactor,
+			Detail:      detail,
+		}
+	}
+
+	data, err := xml.Marshal(v)
+	return string(data), err
+}
+
+func newSoapFault12Code(code *SoapFaultCode) *soapFault12Code {
+	if code == nil {
+		return nil
+	}
+	return &soapFault12Code{Value: code.Value, Subcode: newSoapFault12Code(code.Subcode)}
+}
+
+// Returns the fault code of the given SOAP version for code, "" being a
+// server fault. Codes qualified with another prefix than the ones of the
+// envelope are custom codes, sent as they are.
+func faultCode(code string, soap12 bool) string {
+	local := code
+	if i := strings.Index(code, ":"); i >= 0 {
+		local = code[i+1:]
+	}
+
+	switch local {
+	case "":
+		local = "Server"
+		if soap12 {
+			local = "Receiver"
+		}
+	case "Client", "Sender":
+		local = "Client"
+		if soap12 {
+			local = "Sender"
+		}
+	case "Server", "Receiver":
+		local = "Server"
+		if soap12 {
+			local = "Receiver"
+		}
+	case "VersionMismatch", "MustUnderstand", "DataEncodingUnknown":
+	default:
+		if code != local {
+			return code
+		}
+	}
+	return "soap:" + local
+}
@@ -0,0 +1,175 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type echoRequest struct {
+	XMLName xml.Name `xml:"urn:echo Echo"`
+	Text    string   `xml:"Text"`
+}
+
+type echoResponse struct {
+	XMLName xml.Name `xml:"urn:echo EchoResponse"`
+	Text    string   `xml:"Text"`
+}
+
+type echoFaultDetail struct {
+	XMLName xml.Name `xml:"urn:echo EchoFault"`
+	Reason  string   `xml:"Reason"`
+}
+
+type echoFault struct {
+	*SoapFault
+	FaultDetail *echoFaultDetail
+}
+
+func (f *echoFault) SoapFaultDetail() (*SoapFault, interface{}) {
+	return f.SoapFault, f.FaultDetail
+}
+
+func newEchoHandler(soap12 bool) http.Handler {
+	operations := []*SoapOperation{{
+		Name:    "Echo",
+		Action:  "urn:echo/Echo",
+		Element: xml.Name{Space: "urn:echo", Local: "Echo"},
+		Serve: func(ctx context.Context, header, body string) (interface{}, interface{}, error) {
+			request := &echoRequest{}
+			if err := UnmarshalContent(body, request); err != nil {
+				return nil, nil, NewClientFault(err)
+			}
+			switch request.Text {
+			case "typed":
+				return nil, nil, &echoFault{
+					SoapFault:   &SoapFault{Faultcode: "Client", Faultstring: "typed fault"},
+					FaultDetail: &echoFaultDetail{Reason: "because"},
+				}
+			case "error":
+				return nil, nil, errors.New("failed")
+			}
+			return &echoResponse{Text: request.Text}, nil, nil
+		},
+	}}
+
+	if soap12 {
+		return NewSoap12Handler(operations)
+	}
+	return NewSoapHandler(operations)
+}
+
+func TestSoapHandler(t *testing.T) {
+	ts := httptest.NewServer(newEchoHandler(false))
+	defer ts.Close()
+
+	client := NewSoapClient(ts.URL, false)
+	response := &echoResponse{}
+	if err := client.Call("urn:echo/Echo", &echoRequest{Text: "hello"}, response, nil, nil); err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	if response.Text != "hello" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", response.Text, "hello")
+	}
+
+	// Dispatched on the body element when the action is unknown.
+	response = &echoResponse{}
+	if err := client.Call("", &echoRequest{Text: "by element"}, response, nil, nil); err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	if response.Text != "by element" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", response.Text, "by element")
+	}
+
+	// Literal requests with an id and an href aren't multi-references.
+	request := `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+		<soap:Body><Echo xmlns="urn:echo" id="echo-1"><Text href="#top">linked</Text></Echo></soap:Body>
+	</soap:Envelope>`
+	resp, err := http.Post(ts.URL, "text/xml", strings.NewReader(request))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(data), "<Text>linked</Text>") {
+		t.Errorf("incorrect result\ngot:  %s\nwant: %s", data, "<Text>linked</Text>")
+	}
+}
+
+func TestSoapHandlerFaults(t *testing.T) {
+	ts := httptest.NewServer(newEchoHandler(false))
+	defer ts.Close()
+
+	client := NewSoapClient(ts.URL, false)
+	err := client.Call("urn:echo/Echo", &echoRequest{Text: "typed"}, &echoResponse{}, nil, nil)
+	fault, ok := err.(*SoapFault)
+	if !ok {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, &SoapFault{})
+	}
+	if fault.Faultcode != "soap:Client" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", fault.Faultcode, "soap:Client")
+	}
+	detail := &echoFaultDetail{}
+	if err := fault.UnmarshalDetail(detail); err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	if detail.Reason != "because" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", detail.Reason, "because")
+	}
+
+	err = client.Call("urn:echo/Echo", &echoRequest{Text: "error"}, &echoResponse{}, nil, nil)
+	fault, ok = err.(*SoapFault)
+	if !ok {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, &SoapFault{})
+	}
+	if fault.Faultcode != "soap:Server" || fault.Faultstring != "failed" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", fault, &SoapFault{Faultcode: "soap:Server", Faultstring: "failed"})
+	}
+}
+
+func TestSoap12HandlerFault(t *testing.T) {
+	ts := httptest.NewServer(newEchoHandler(true))
+	defer ts.Close()
+
+	body := `<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope"><soap:Body><Unknown xmlns="urn:echo"/></soap:Body></soap:Envelope>`
+	res, err := http.Post(ts.URL, "application/soap+xml", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	defer res.Body.Close()
+
+	data, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode != http.StatusInternalServerError {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", res.StatusCode, http.StatusInternalServerError)
+	}
+	if !strings.Contains(string(data), "<soap:Value>soap:Sender</soap:Value>") {
+		t.Errorf("SOAP 1.2 fault without a Sender code: %s", data)
+	}
+}
+
+func TestFaultCode(t *testing.T) {
+	for _, test := range []struct {
+		code   string
+		soap12 bool
+		want   string
+	}{
+		{"", false, "soap:Server"},
+		{"", true, "soap:Receiver"},
+		{"Client", true, "soap:Sender"},
+		{"soap12:Receiver", false, "soap:Server"},
+		{"MustUnderstand", true, "soap:MustUnderstand"},
+		{"app:Custom", false, "app:Custom"},
+	} {
+		if got := faultCode(test.code, test.soap12); got != test.want {
+			t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", got, test.want)
+		}
+	}
+}
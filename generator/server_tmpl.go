@@ -0,0 +1,61 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+var serverTmpl = `
+{{range .}}
+{{if hasSoapBinding .Name}}
+	{{$portTypeName := .Name}}
+	{{$portType := .Name | makePublic}}
+	// {{$portType}}Server is implemented by the services of the {{.Name}} port type,
+	// see New{{$portType}}Handler. Errors other than *gowsdl.SoapFault and the
+	// typed faults of the operations are sent as server faults.
+	type {{$portType}}Server interface {
+		{{range .Operations}}
+		{{$sig := operationSignature . $portTypeName}}
+		{{if ne .Doc ""}}/* {{.Doc}} */{{end}}
+		{{$sig.Method}}(ctx context.Context, {{if ne $sig.Request ""}}request {{$sig.Request}}, {{end}}{{if ne $sig.RequestHeader ""}}requestHeader {{$sig.RequestHeader}}{{end}}) ({{$sig.Response}}, {{if ne $sig.ResponseHeader ""}}{{$sig.ResponseHeader}}, {{end}}error)
+		{{end}}
+	}
+
+	// New{{$portType}}Handler returns an http.Handler serving the operations of the
+	// {{.Name}} port type with service.
+	func New{{$portType}}Handler(service {{$portType}}Server) http.Handler {
+		return gowsdl.New{{if isSoap12 .Name}}Soap12{{else}}Soap{{end}}Handler([]*gowsdl.SoapOperation{
+			{{range .Operations}}
+			{{$sig := operationSignature . $portTypeName}}
+			{
+				Name:    {{printf "%q" .Name}},
+				Action:  {{printf "%q" $sig.Action}},
+				Element: xml.Name{Space: {{printf "%q" $sig.Element.Space}}, Local: {{printf "%q" $sig.Element.Local}}},
+				Serve: func(ctx context.Context, header, body string) (interface{}, interface{}, error) {
+					{{if ne $sig.Request ""}}
+					request := &{{replaceStar $sig.Request}}{}
+					if err := gowsdl.UnmarshalContent(body, request); err != nil {
+						return nil, nil, gowsdl.NewClientFault(err)
+					}
+					if err := gowsdl.Validate(request); err != nil {
+						return nil, nil, gowsdl.NewClientFault(err)
+					}
+					{{end}}
+					{{if ne $sig.RequestHeader ""}}
+					requestHeader := &{{replaceStar $sig.RequestHeader}}{}
+					if err := gowsdl.UnmarshalContent(header, requestHeader); err != nil {
+						return nil, nil, gowsdl.NewClientFault(err)
+					}
+					{{end}}
+					{{if ne $sig.ResponseHeader ""}}
+					return service.{{$sig.Method}}(ctx, {{if ne $sig.Request ""}}request, {{end}}{{if ne $sig.RequestHeader ""}}requestHeader{{end}})
+					{{else}}
+					response, err := service.{{$sig.Method}}(ctx, {{if ne $sig.Request ""}}request, {{end}}{{if ne $sig.RequestHeader ""}}requestHeader{{end}})
+					return response, nil, err
+					{{end}}
+				},
+			},
+			{{end}}
+		})
+	}
+{{end}}
+{{end}}
+`
@@ -5,19 +5,33 @@ package generator
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/xml"
+	"errors"
+	"fmt"
 	"gopkg.in/inconshreveable/log15.v2"
+	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/http/httputil"
+	"net/url"
 	"os"
+	"sort"
+	"strings"
+	"time"
 )
 
 var _ os.File
 
 var Log = log15.New()
 
+const (
+	soapEnvelopeNamespace   = "http://schemas.xmlsoap.org/soap/envelope/"
+	soap12EnvelopeNamespace = "http://www.w3.org/2003/05/soap-envelope"
+)
+
 func init() {
 	Log.SetHandler(log15.DiscardHandler())
 //	handler := log15.StreamHandler(os.Stdout, log15.LogfmtFormat())
@@ -30,10 +44,27 @@ type SoapEnvelope struct {
 	Body    SoapBody    `xml:"http://schemas.xmlsoap.org/soap/envelope/ Body"`
 }
 
+// SoapHeader is both sent with a request and filled in by the call (MessageID
+// and, from the response, Content, RespHeader, RelatesTo and Attachments), the
+// generated operations also set ReqHeader and RespHeader. A header is used by
+// a single call, calls running concurrently need a header each.
 type SoapHeader struct {
+	// ReqHeader is sent in the header of the request and the header of the
+	// response is decoded into RespHeader, ie. the typed headers of the
+	// generated operations.
 	ReqHeader interface{}
-	RespHeader interface{}
+	RespHeader interface{} `xml:"-"`
 	BodyAttributes interface{}
+	// Additional header blocks, the client appends its own ones (ie.
+	// wsse:Security) to a copy of the header when sending a request.
+	Headers []interface{}
+	// WS-Addressing message id of the request and wsa:RelatesTo of the
+	// response, set by clients using WS-Addressing.
+	MessageID string `xml:"-"`
+	RelatesTo string `xml:"-"`
+	// Parts of a multipart/related (SOAP with Attachments or MTOM) response
+	// other than the envelope, see also ContextWithAttachments.
+	Attachments Attachments `xml:"-"`
 	Content string     `xml:",innerxml"`
 }
 
@@ -45,32 +76,262 @@ type SoapBody struct {
 	Transaction string `xml:"Transaction,attr,omitempty"`
 }
 
+// SoapFault is the fault returned by the service, it decodes both the SOAP 1.1
+// (faultcode, faultstring, faultactor, detail) and the SOAP 1.2 (Code, Reason,
+// Node, Role, Detail) shapes. SOAP 1.2 faults also fill in the SOAP 1.1
+// fields so callers can look at a fault the same way regardless of version.
 type SoapFault struct {
 	Faultcode   string `xml:"faultcode,omitempty"`
 	Faultstring string `xml:"faultstring,omitempty"`
 	Faultactor  string `xml:"faultactor,omitempty"`
-	Detail      string `xml:"detail,omitempty"`
+	// Raw XML of the fault detail, see UnmarshalDetail.
+	Detail string `xml:"detail,omitempty"`
+
+	// SOAP 1.2 only
+	Code    *SoapFaultCode    `xml:"-"`
+	Reasons []SoapFaultReason `xml:"-"`
+	Node    string            `xml:"-"`
+	Role    string            `xml:"-"`
+}
+
+// SoapFaultCode is a SOAP 1.2 fault code along with its chain of subcodes.
+type SoapFaultCode struct {
+	Value   string         `xml:"Value"`
+	Subcode *SoapFaultCode `xml:"Subcode"`
+}
+
+// SoapFaultReason is a SOAP 1.2 fault reason in a given language.
+type SoapFaultReason struct {
+	Lang string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Text string `xml:",chardata"`
+}
+
+type soapFaultDetail struct {
+	Content string
+}
+
+// UnmarshalXML keeps the content of the detail re-serialised with the
+// namespaces of its elements and attributes, as they may be declared by the
+// ancestors of the detail (ie. the Envelope), so that it can be decoded on
+// its own. The namespace declarations of the content are kept as they are,
+// for the prefixes of QName values.
+func (detail *soapFaultDetail) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	buffer := &bytes.Buffer{}
+	e := xml.NewEncoder(buffer)
+	for depth := 0; ; {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			token = namespacedStart(t)
+		case xml.EndElement:
+			if depth == 0 {
+				if err := e.Flush(); err != nil {
+					return err
+				}
+				detail.Content = buffer.String()
+				return nil
+			}
+			depth--
+		}
+		if err := e.EncodeToken(xml.CopyToken(token)); err != nil {
+			return err
+		}
+	}
+}
+
+// Returns start, decoded with its names translated to their namespace, with
+// its namespace declarations as plain attributes, the encoder declaring the
+// namespaces of the names itself.
+func namespacedStart(start xml.StartElement) xml.StartElement {
+	attrs := make([]xml.Attr, 0, len(start.Attr))
+	for _, attr := range start.Attr {
+		switch {
+		case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+			// Default namespace, declared by the encoder for the names.
+			continue
+		case attr.Name.Space == "xmlns":
+			attr.Name = xml.Name{Local: "xmlns:" + attr.Name.Local}
+		}
+		attrs = append(attrs, attr)
+	}
+	start.Attr = attrs
+	return start
+}
+
+// Soap12Envelope is the SOAP 1.2 counterpart of SoapEnvelope, it only differs
+// in the envelope namespace.
+type Soap12Envelope struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2003/05/soap-envelope Envelope"`
+	Header  *SoapHeader `xml:"http://www.w3.org/2003/05/soap-envelope Header,omitempty"`
+	Body    Soap12Body  `xml:"http://www.w3.org/2003/05/soap-envelope Body"`
+}
+
+type Soap12Body struct {
+	Fault   *SoapFault `xml:"http://www.w3.org/2003/05/soap-envelope Fault,omitempty"`
+	Content string     `xml:",innerxml"`
 }
 
 type SoapClient struct {
-	url string
-	tls bool
+	url    string
+	tls    bool
+	soap12 bool
+
+	httpClient  *http.Client
+	transport   http.RoundTripper
+	tlsConfig   *tls.Config
+	proxy       func(*http.Request) (*url.URL, error)
+	timeout     time.Duration
+	dialTimeout time.Duration
+	recordDir   string
+
+	security   *WSSecurity
+	signature  *WSSignature
+	addressing bool
+	mtom       bool
+	validate   bool
 }
 
 func (f *SoapFault) Error() string {
 	return f.Faultstring
 }
 
-func NewSoapClient(url string, tls bool) *SoapClient {
-	return &SoapClient{
-		url: url,
-		tls: tls,
+// UnmarshalXML decodes SOAP 1.1 and SOAP 1.2 faults alike.
+func (f *SoapFault) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var fault struct {
+		Faultcode   string           `xml:"faultcode"`
+		Faultstring string           `xml:"faultstring"`
+		Faultactor  string           `xml:"faultactor"`
+		Detail      *soapFaultDetail `xml:"detail"`
+
+		Code     *SoapFaultCode    `xml:"Code"`
+		Reasons  []SoapFaultReason `xml:"Reason>Text"`
+		Node     string            `xml:"Node"`
+		Role     string            `xml:"Role"`
+		Detail12 *soapFaultDetail  `xml:"Detail"`
+	}
+
+	if err := d.DecodeElement(&fault, &start); err != nil {
+		return err
+	}
+
+	*f = SoapFault{
+		Faultcode:   fault.Faultcode,
+		Faultstring: fault.Faultstring,
+		Faultactor:  fault.Faultactor,
+		Code:        fault.Code,
+		Reasons:     fault.Reasons,
+		Node:        fault.Node,
+		Role:        fault.Role,
+	}
+
+	if fault.Detail != nil {
+		f.Detail = fault.Detail.Content
+	} else if fault.Detail12 != nil {
+		f.Detail = fault.Detail12.Content
+	}
+
+	if f.Code != nil && f.Faultcode == "" {
+		f.Faultcode = f.Code.Value
+	}
+	if len(f.Reasons) > 0 && f.Faultstring == "" {
+		f.Faultstring = f.Reasons[0].Text
+	}
+	if f.Role != "" && f.Faultactor == "" {
+		f.Faultactor = f.Role
+	}
+
+	return nil
+}
+
+// Reason returns the fault reason in the given language, falling back to
+// Faultstring when the fault has no reason in that language.
+func (f *SoapFault) Reason(lang string) string {
+	for _, reason := range f.Reasons {
+		if reason.Lang == lang {
+			return reason.Text
+		}
+	}
+	return f.Faultstring
+}
+
+// Subcodes returns the values of the SOAP 1.2 subcodes, outermost first.
+func (f *SoapFault) Subcodes() []string {
+	var subcodes []string
+	if f.Code == nil {
+		return subcodes
+	}
+
+	for code := f.Code.Subcode; code != nil; code = code.Subcode {
+		subcodes = append(subcodes, code.Value)
+	}
+	return subcodes
+}
+
+// DetailName returns the qualified name of the root element of the fault
+// detail, or the zero name when the fault has no detail.
+func (f *SoapFault) DetailName() xml.Name {
+	d := xml.NewDecoder(strings.NewReader(f.Detail))
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return xml.Name{}
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name
+		}
 	}
 }
 
+// UnmarshalDetail decodes the fault detail into v, which is usually the
+// type of the wsdl:fault message of the operation.
+func (f *SoapFault) UnmarshalDetail(v interface{}) error {
+	if strings.TrimSpace(f.Detail) == "" {
+		return errors.New("fault has no detail")
+	}
+	return xml.Unmarshal([]byte(f.Detail), v)
+}
+
+func NewSoapClient(url string, tls bool, opts ...ClientOption) *SoapClient {
+	return newSoapClient(url, tls, false, opts)
+}
+
+// NewSoap12Client returns a client that talks SOAP 1.2 instead of SOAP 1.1,
+// the action is sent as a parameter of the application/soap+xml content
+// type instead of using the SOAPAction header.
+func NewSoap12Client(url string, tls bool, opts ...ClientOption) *SoapClient {
+	return newSoapClient(url, tls, true, opts)
+}
+
+func newSoapClient(url string, tls, soap12 bool, opts []ClientOption) *SoapClient {
+	s := &SoapClient{
+		url:         url,
+		tls:         tls,
+		soap12:      soap12,
+		dialTimeout: timeout,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.httpClient = s.newHTTPClient()
+
+	return s
+}
+
 func (s *SoapClient) Call(soapAction string, request, response interface{}, header *SoapHeader, configureRequest func(*http.Request)) error {
-	envelope := SoapEnvelope{
-		//Header: header,
+	return s.CallContext(context.Background(), soapAction, request, response, header, configureRequest)
+}
+
+// CallContext is like Call but the HTTP request is bound to ctx, so canceling
+// ctx or reaching its deadline aborts the call. The response fields of header
+// are written, see SoapHeader.
+func (s *SoapClient) CallContext(ctx context.Context, soapAction string, request, response interface{}, header *SoapHeader, configureRequest func(*http.Request)) error {
+	if header == nil {
+		header = &SoapHeader{}
 	}
 
 	//TODO VERIFY HEADER AND BODY ATTRIBUTES!
@@ -86,23 +347,36 @@ func (s *SoapClient) Call(soapAction string, request, response interface{}, head
 		header.BodyAttributes = nil
 	}
 
-	envelope.Header = header
-
+	var content string
+	attachments := &mimeParts{}
 	if request != nil {
-		reqXml, err := xml.Marshal(request)
+		if s.validate {
+			if err := Validate(request); err != nil {
+				return err
+			}
+		}
+		reqXml, parts, err := marshalRequest(request, s.mtom)
 		if err != nil {
 			return err
 		}
 
-		envelope.Body.Content = string(reqXml)
+		content = reqXml
+		attachments = parts
 //		envelope.Body.Attributes = bodyAttributes
 	}
+	reqHeader, err := s.requestHeader(header, soapAction)
+	if err != nil {
+		return err
+	}
+	header.MessageID = reqHeader.MessageID
+
+	envelope := newEnvelope(reqHeader, content, s.soap12)
 	buffer := &bytes.Buffer{}
 
 	encoder := xml.NewEncoder(buffer)
 	encoder.Indent("  ", "    ")
 
-	err := encoder.Encode(envelope)
+	err = encoder.Encode(envelope)
 	if err == nil {
 		err = encoder.Flush()
 	}
@@ -110,10 +384,41 @@ func (s *SoapClient) Call(soapAction string, request, response interface{}, head
 		return err
 	}
 
-	req, err := http.NewRequest("POST", s.url, buffer)
+	if s.signature != nil {
+		signed, err := s.signature.sign(buffer.Bytes())
+		if err != nil {
+			return err
+		}
+		buffer = bytes.NewBuffer(signed)
+	}
 
-	req.Header.Add("Content-Type", "text/xml; charset=\"utf-8\"")
-	if soapAction != "" {
+	// SOAP 1.2 drops the SOAPAction header in favour of the action
+	// parameter of the media type.
+	envelopeType, action := "text/xml", ""
+	if s.soap12 {
+		envelopeType, action = "application/soap+xml", soapAction
+	}
+
+	var reqBody io.Reader = buffer
+	contentType := envelopeType + "; charset=\"utf-8\""
+	if action != "" {
+		contentType += "; action=\"" + action + "\""
+	}
+	if s.mtom {
+		reqBody, contentType, err = newMTOMMessage(buffer.Bytes(), attachments, envelopeType, action)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest("POST", s.url, reqBody)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	req.Header.Add("Content-Type", contentType)
+	if !s.soap12 && soapAction != "" {
 		req.Header.Add("SOAPAction", soapAction)
 	}
 
@@ -122,18 +427,10 @@ func (s *SoapClient) Call(soapAction string, request, response interface{}, head
 	}
 
 	Log.Debug("request", "request", req,
-		"Header", log15.Lazy{func() string { r, _ := httputil.DumpRequestOut(req, true); return string(r) }},
+		"Header", log15.Lazy{Fn: func() string { r, _ := httputil.DumpRequestOut(req, true); return string(r) }},
 	)
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: s.tls,
-		},
-		Dial: dialTimeout,
-	}
-
-	client := &http.Client{Transport: tr}
-	res, err := client.Do(req)
+	res, err := s.httpClient.Do(req)
 	if err != nil {
 		Log.Debug("Client error", "err", err)
 		return err
@@ -141,48 +438,371 @@ func (s *SoapClient) Call(soapAction string, request, response interface{}, head
 	defer res.Body.Close()
 
 	rawbody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		// The context may be done after the headers were received.
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("%w: %v", ctxErr, err)
+		}
+		return err
+	}
 	if len(rawbody) == 0 {
 		Log.Warn("empty response")
 		return nil
 	}
-	Log.Debug("Raw response", "url", s.url, "rawbody", log15.Lazy{func() string { return string(rawbody) }})
+	Log.Debug("Raw response", "url", s.url, "rawbody", log15.Lazy{Fn: func() string { return string(rawbody) }})
+
+	rawbody, header.Attachments, err = unpackEnvelope(res.Header.Get("Content-Type"), rawbody)
+	if err != nil {
+		return err
+	}
+	if attachments, ok := ctx.Value(attachmentsKey{}).(Attachments); ok {
+		for contentID, attachment := range header.Attachments {
+			attachments[contentID] = attachment
+		}
+	}
 
-	respEnvelope := &SoapEnvelope{}
+	if s.signature != nil && s.signature.VerifyResponses {
+		if err := s.signature.verify(rawbody); err != nil {
+			return err
+		}
+	}
 
-	err = xml.Unmarshal(rawbody, respEnvelope)
+	respHeader, body, fault, err := parseEnvelope(rawbody, s.soap12)
 	if err != nil {
 		return err
 	}
 
-	body := respEnvelope.Body.Content
-	fault := respEnvelope.Body.Fault
-	if(respEnvelope.Header != nil){
-		header.Content = respEnvelope.Header.Content
+	if(respHeader != nil){
+		header.Content = respHeader.Content
+	}
+	if s.addressing {
+		header.RelatesTo = addressingRelatesTo(rawbody)
+	}
+
+	// The response header is decoded even for faults, which may come with
+	// header faults.
+	if(header.Content != "" && header.RespHeader != nil){
+		Log.Debug("Header","content",header.Content)
+		if err := UnmarshalContent(header.Content, header.RespHeader); err != nil {
+			return err
+		}
+		Log.Debug("Header","head",header.RespHeader)
 	}
 
 	if body == "" {
-		Log.Warn("empty response body", "envelope", respEnvelope, "body", body)
+		Log.Warn("empty response body", "body", body)
 		return nil
 	}
 
-	Log.Debug("response", "envelope", respEnvelope, "body", body)
+	Log.Debug("response", "body", body)
 	if fault != nil {
 		return fault
 	}
 
-	if(header.Content != ""){
-		Log.Debug("Header","content",header.Content)
-		err = xml.Unmarshal([]byte(header.Content), header.RespHeader)
+	return UnmarshalContent(body, response)
+}
+
+// Returns the header to send, a copy of the caller's header with the header
+// blocks of the client appended so they don't pile up in the caller's
+// header.
+func (s *SoapClient) requestHeader(header *SoapHeader, soapAction string) (*SoapHeader, error) {
+	reqHeader := *header
+	reqHeader.ReqHeader = namedElement(header.ReqHeader)
+	reqHeader.Headers = nil
+	for _, block := range header.Headers {
+		reqHeader.Headers = append(reqHeader.Headers, namedElement(block))
+	}
+
+	wsSecurity := s.security
+	if wsSecurity == nil && s.signature != nil {
+		// The signature goes in a wsse:Security header, even an empty one.
+		wsSecurity = &WSSecurity{}
+	}
+	if wsSecurity == nil && !s.addressing {
+		return &reqHeader, nil
+	}
+
+	if s.addressing {
+		messageID, err := newMessageID()
 		if err != nil {
-			return err
+			return nil, err
 		}
-		Log.Debug("Header","head",header.RespHeader)
+		reqHeader.MessageID = messageID
+		reqHeader.Headers = append(reqHeader.Headers, addressingHeaders(soapAction, s.url, messageID, s.soap12)...)
 	}
 
-	err = xml.Unmarshal([]byte(body), response)
+	if wsSecurity != nil {
+		security, err := wsSecurity.Header(s.soap12)
+		if err != nil {
+			return nil, err
+		}
+		reqHeader.Headers = append(reqHeader.Headers, security)
+	}
+
+	return &reqHeader, nil
+}
+
+// Returns the envelope of a message and, for multipart messages, its other
+// parts. The envelope is the root part of multipart messages, with its
+// xop:Include elements resolved for MTOM messages.
+func unpackEnvelope(contentType string, data []byte) ([]byte, Attachments, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.EqualFold(mediaType, "multipart/related") {
+		return data, nil, nil
+	}
+
+	root, parts, err := parseMultipartRelated(data, params)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	return nil
+	attachments := make(Attachments, len(parts))
+	for _, part := range parts {
+		attachments[part.ContentID] = part
+	}
+
+	rootType, _, _ := mime.ParseMediaType(root.ContentType)
+	if strings.EqualFold(rootType, xopMediaType) {
+		envelope, err := resolveXOP(root.Data, parts)
+		return envelope, attachments, err
+	}
+	return root.Data, attachments, nil
+}
+
+// Attachment returns the attachment of the response referenced by ref, see
+// Attachments.Get.
+func (h *SoapHeader) Attachment(ref string) *Attachment {
+	return h.Attachments.Get(ref)
+}
+
+// Attachments are the parts of a multipart/related response other than the
+// envelope, keyed by Content-ID.
+type Attachments map[string]*Attachment
+
+// Get returns the attachment referenced by ref, either a Content-ID or a cid:
+// URL as used by swaRef elements, or nil.
+func (a Attachments) Get(ref string) *Attachment {
+	contentID := strings.Trim(ref, "<>")
+	if strings.HasPrefix(contentID, "cid:") {
+		contentID = strings.TrimPrefix(contentID, "cid:")
+		if unescaped, err := url.PathUnescape(contentID); err == nil {
+			contentID = unescaped
+		}
+	}
+	return a[contentID]
+}
+
+type attachmentsKey struct{}
+
+// ContextWithAttachments returns a copy of ctx whose calls add the attachments
+// of their response to attachments. It gives access to the attachments of
+// calls made without a header, ie. through the generated operations:
+//
+//	attachments := gowsdl.Attachments{}
+//	ctx = gowsdl.ContextWithAttachments(ctx, attachments)
+//	response, err := service.GetReportContext(ctx, request, nil, nil)
+//	report := attachments.Get(response.Report)
+func ContextWithAttachments(ctx context.Context, attachments Attachments) context.Context {
+	return context.WithValue(ctx, attachmentsKey{}, attachments)
+}
+
+// Wraps already marshalled content in the envelope of the given SOAP version.
+func newEnvelope(header *SoapHeader, content string, soap12 bool) interface{} {
+	if soap12 {
+		return Soap12Envelope{
+			Header: header,
+			Body:   Soap12Body{Content: content},
+		}
+	}
+
+	return SoapEnvelope{
+		Header: header,
+		Body:   SoapBody{Content: content},
+	}
+}
+
+// Unmarshals an envelope of the given SOAP version, returning its header, the
+// body content and the fault, if the body carries one. The content of the
+// header and of the body is self-contained, see readContent, as the prefixes
+// it uses are usually declared by the Envelope.
+func parseEnvelope(data []byte, soap12 bool) (*SoapHeader, string, *SoapFault, error) {
+	envelopeNamespace := soapEnvelopeNamespace
+	if soap12 {
+		envelopeNamespace = soap12EnvelopeNamespace
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var envelope xml.StartElement
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			envelope = start
+			break
+		}
+	}
+	if envelope.Name.Space != envelopeNamespace || envelope.Name.Local != "Envelope" {
+		return nil, "", nil, fmt.Errorf("expected element <Envelope> in name space %s but have <%s> in name space %s", envelopeNamespace, envelope.Name.Local, envelope.Name.Space)
+	}
+	scope := namespaceScope(nil, envelope)
+
+	var header *SoapHeader
+	var body string
+	var fault *SoapFault
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Space == envelopeNamespace && t.Name.Local == "Header":
+				content, err := readContent(d, namespaceScope(scope, t))
+				if err != nil {
+					return nil, "", nil, err
+				}
+				header = &SoapHeader{Content: content}
+			case t.Name.Space == envelopeNamespace && t.Name.Local == "Body":
+				body, fault, err = readBody(d, namespaceScope(scope, t), envelopeNamespace)
+				if err != nil {
+					return nil, "", nil, err
+				}
+			default:
+				if err := d.Skip(); err != nil {
+					return nil, "", nil, err
+				}
+			}
+		case xml.EndElement:
+			return header, body, fault, nil
+		}
+	}
+}
+
+// Reads the content of a Body, see readContent, and decodes its fault.
+func readBody(d *xml.Decoder, scope map[string]string, envelopeNamespace string) (string, *SoapFault, error) {
+	buffer := &bytes.Buffer{}
+	var fault *SoapFault
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return "", nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			content, err := readElement(d, t, scope)
+			if err != nil {
+				return "", nil, err
+			}
+			buffer.WriteString(content)
+
+			if t.Name.Space == envelopeNamespace && t.Name.Local == "Fault" {
+				fault = &SoapFault{}
+				if err := xml.Unmarshal([]byte(content), fault); err != nil {
+					return "", nil, err
+				}
+			}
+		case xml.CharData:
+			xml.EscapeText(buffer, t)
+		case xml.EndElement:
+			return buffer.String(), fault, nil
+		}
+	}
+}
+
+// Reads the content of the element whose start was just read from d, up to
+// its end, as self-contained XML: names are written with their namespace and
+// the prefixes in scope, ie. the ones declared by the Envelope, are declared
+// again on the top-level elements for the QName values of the content (ie.
+// xsi:type) and its attributes.
+func readContent(d *xml.Decoder, scope map[string]string) (string, error) {
+	buffer := &bytes.Buffer{}
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return "", err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			content, err := readElement(d, t, scope)
+			if err != nil {
+				return "", err
+			}
+			buffer.WriteString(content)
+		case xml.CharData:
+			xml.EscapeText(buffer, t)
+		case xml.EndElement:
+			return buffer.String(), nil
+		}
+	}
+}
+
+// Reads the element started by start as self-contained XML, see readContent.
+func readElement(d *xml.Decoder, start xml.StartElement, scope map[string]string) (string, error) {
+	buffer := &bytes.Buffer{}
+	e := xml.NewEncoder(buffer)
+
+	declared := namespaceDecls(start.Attr)
+	root := namespacedStart(start)
+	prefixes := make([]string, 0, len(scope))
+	for prefix := range scope {
+		if _, ok := declared[prefix]; !ok {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		root.Attr = append(root.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: scope[prefix]})
+	}
+
+	token := xml.Token(root)
+	for depth := 0; ; {
+		if err := e.EncodeToken(xml.CopyToken(token)); err != nil {
+			return "", err
+		}
+
+		var err error
+		if token, err = d.Token(); err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			token = namespacedStart(t)
+		case xml.EndElement:
+			if depth == 0 {
+				if err := e.EncodeToken(t); err != nil {
+					return "", err
+				}
+				if err := e.Flush(); err != nil {
+					return "", err
+				}
+				return buffer.String(), nil
+			}
+			depth--
+		case xml.ProcInst, xml.Directive:
+			token = xml.CharData(nil)
+		}
+	}
+}
+
+// Returns the prefixes declared for the content of the element started by
+// start, ie. the ones of its ancestors with its own declarations.
+func namespaceScope(parent map[string]string, start xml.StartElement) map[string]string {
+	scope := make(map[string]string, len(parent))
+	for prefix, namespace := range parent {
+		scope[prefix] = namespace
+	}
+	for prefix, namespace := range namespaceDecls(start.Attr) {
+		// The default namespace is declared by the encoder for the names.
+		if prefix != "" {
+			scope[prefix] = namespace
+		}
+	}
+	return scope
 }
@@ -0,0 +1,167 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ClientOption configures a SoapClient, it is passed to NewSoapClient,
+// NewSoap12Client and to the generated New<PortType> constructors.
+type ClientOption func(*SoapClient)
+
+// WithHTTPClient makes the SoapClient send its requests through c. The client
+// is used as is, the other transport options are ignored.
+func WithHTTPClient(c *http.Client) ClientOption {
+	return func(s *SoapClient) {
+		s.httpClient = c
+	}
+}
+
+// WithTransport makes the SoapClient send its requests through rt instead of
+// its own http.Transport. WithTLSConfig, WithProxy and WithDialTimeout are
+// ignored when a transport is given.
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(s *SoapClient) {
+		s.transport = rt
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of the transport, ie. to present
+// client certificates or to trust private root CAs.
+func WithTLSConfig(config *tls.Config) ClientOption {
+	return func(s *SoapClient) {
+		s.tlsConfig = config
+	}
+}
+
+// WithProxy sets the proxy function of the transport, ie. http.ProxyURL or
+// http.ProxyFromEnvironment.
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) ClientOption {
+	return func(s *SoapClient) {
+		s.proxy = proxy
+	}
+}
+
+// WithTimeout limits the time a call can take, including reading the
+// response. Zero means no timeout.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(s *SoapClient) {
+		s.timeout = timeout
+	}
+}
+
+// WithDialTimeout limits the time spent connecting to the service, it
+// defaults to 30 seconds.
+func WithDialTimeout(timeout time.Duration) ClientOption {
+	return func(s *SoapClient) {
+		s.dialTimeout = timeout
+	}
+}
+
+// WithWSSecurity adds a wsse:Security header, built from security, to every
+// call.
+func WithWSSecurity(security *WSSecurity) ClientOption {
+	return func(s *SoapClient) {
+		s.security = security
+	}
+}
+
+// WithWSSignature signs every request with the certificate and key of
+// signature and, if signature.VerifyResponses is set, rejects the responses
+// which are not properly signed.
+func WithWSSignature(signature *WSSignature) ClientOption {
+	return func(s *SoapClient) {
+		s.signature = signature
+	}
+}
+
+// WithWSAddressing adds the WS-Addressing wsa:Action, wsa:To, wsa:MessageID
+// and wsa:ReplyTo headers to every call, the action being the SOAP action of
+// the call. The generated clients of bindings using WS-Addressing set it,
+// passing WithoutWSAddressing to their constructor turns it off.
+func WithWSAddressing() ClientOption {
+	return func(s *SoapClient) {
+		s.addressing = true
+	}
+}
+
+// WithoutWSAddressing turns off the WS-Addressing headers of the calls, ie.
+// for a generated client whose service doesn't actually require them.
+func WithoutWSAddressing() ClientOption {
+	return func(s *SoapClient) {
+		s.addressing = false
+	}
+}
+
+// WithMTOM sends the requests as MTOM/XOP multipart/related messages, the
+// non empty Base64Binary values being sent as binary attachments instead of
+// being base64 encoded in the envelope. MTOM responses are always accepted.
+func WithMTOM() ClientOption {
+	return func(s *SoapClient) {
+		s.mtom = true
+	}
+}
+
+// WithValidation checks the requests with Validate before sending them, ie.
+// that at most one branch of their choices is set. Invalid requests are
+// returned as errors instead of being sent.
+func WithValidation() ClientOption {
+	return func(s *SoapClient) {
+		s.validate = true
+	}
+}
+
+// WithRecording saves every call of the SoapClient to dir, see NewRecorder.
+// The requests are sent through the transport of the client, it is ignored
+// along with WithHTTPClient.
+func WithRecording(dir string) ClientOption {
+	return func(s *SoapClient) {
+		s.recordDir = dir
+	}
+}
+
+// WithReplay answers the calls of the SoapClient with the responses recorded
+// to dir by WithRecording, without any network access. See NewReplayer.
+func WithReplay(dir string) ClientOption {
+	return WithTransport(NewReplayer(dir))
+}
+
+// Builds the http.Client shared by every call of the SoapClient, so that
+// connections are pooled and kept alive between calls.
+func (s *SoapClient) newHTTPClient() *http.Client {
+	if s.httpClient != nil {
+		return s.httpClient
+	}
+
+	transport := s.transport
+	if transport == nil {
+		tlsConfig := &tls.Config{}
+		if s.tlsConfig != nil {
+			tlsConfig = s.tlsConfig.Clone()
+		}
+		if s.tls {
+			tlsConfig.InsecureSkipVerify = true
+		}
+
+		dialer := &net.Dialer{Timeout: s.dialTimeout}
+		transport = &http.Transport{
+			TLSClientConfig: tlsConfig,
+			Proxy:           s.proxy,
+			DialContext:     dialer.DialContext,
+		}
+	}
+	if s.recordDir != "" {
+		transport = NewRecorder(s.recordDir, transport)
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   s.timeout,
+	}
+}
@@ -0,0 +1,89 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const pongEnvelope = `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+	<soap:Body><PingResponse xmlns="http://example.com/ping"><Message>pong</Message></PingResponse></soap:Body>
+</soap:Envelope>`
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestWithTransport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(pongEnvelope))
+	}))
+	defer ts.Close()
+
+	calls := 0
+	transport := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return http.DefaultTransport.RoundTrip(r)
+	})
+
+	client := NewSoapClient(ts.URL, false, WithTransport(transport))
+	for i := 0; i < 2; i++ {
+		response := &pingResponse{}
+		err := client.Call("", &pingRequest{}, response, &SoapHeader{}, nil)
+		if err != nil || response.Message != "pong" {
+			t.Fatalf("incorrect result\ngot:  %#v, %#v\nwant: %#v", response.Message, err, "pong")
+		}
+	}
+
+	if calls != 2 {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", calls, 2)
+	}
+}
+
+func TestWithTLSConfig(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(pongEnvelope))
+	}))
+	defer ts.Close()
+
+	// Not trusting the test server certificate must fail
+	err := NewSoapClient(ts.URL, false).Call("", &pingRequest{}, &pingResponse{}, &SoapHeader{}, nil)
+	if err == nil {
+		t.Fatalf("expected an unknown authority error")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ts.Certificate())
+	client := NewSoapClient(ts.URL, false, WithTLSConfig(&tls.Config{RootCAs: roots}))
+
+	response := &pingResponse{}
+	err = client.Call("", &pingRequest{}, response, &SoapHeader{}, nil)
+	if err != nil || response.Message != "pong" {
+		t.Errorf("incorrect result\ngot:  %#v, %#v\nwant: %#v", response.Message, err, "pong")
+	}
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(pongEnvelope))
+	}))
+	defer ts.Close()
+
+	client := NewSoapClient(ts.URL, false, WithHTTPClient(ts.Client()))
+	if client.httpClient != ts.Client() {
+		t.Fatalf("the given http.Client is not used")
+	}
+
+	response := &pingResponse{}
+	err := client.Call("", &pingRequest{}, response, &SoapHeader{}, nil)
+	if err != nil || response.Message != "pong" {
+		t.Errorf("incorrect result\ngot:  %#v, %#v\nwant: %#v", response.Message, err, "pong")
+	}
+}
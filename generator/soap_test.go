@@ -4,9 +4,429 @@
 package generator
 
 import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
+type pingRequest struct {
+	XMLName xml.Name `xml:"http://example.com/ping Ping"`
+	Message string   `xml:"Message"`
+}
+
+type pingResponse struct {
+	XMLName xml.Name `xml:"http://example.com/ping PingResponse"`
+	Message string   `xml:"Message"`
+}
+
 func TestSoapCall(t *testing.T) {
 
 }
+
+func TestSoap12Call(t *testing.T) {
+	var contentType, soapAction, body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		soapAction = r.Header.Get("SOAPAction")
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+
+		w.Header().Set("Content-Type", "application/soap+xml")
+		w.Write([]byte(`<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope">
+			<env:Body><PingResponse xmlns="http://example.com/ping"><Message>pong</Message></PingResponse></env:Body>
+		</env:Envelope>`))
+	}))
+	defer ts.Close()
+
+	client := NewSoap12Client(ts.URL, false)
+	response := &pingResponse{}
+	err := client.Call("http://example.com/ping/Ping", &pingRequest{Message: "ping"}, response, &SoapHeader{}, nil)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	want := `application/soap+xml; charset="utf-8"; action="http://example.com/ping/Ping"`
+	if contentType != want {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", contentType, want)
+	}
+	if soapAction != "" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", soapAction, "")
+	}
+	if !strings.Contains(body, `xmlns="http://www.w3.org/2003/05/soap-envelope"`) {
+		t.Errorf("request is not a SOAP 1.2 envelope: %s", body)
+	}
+	if response.Message != "pong" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", response.Message, "pong")
+	}
+}
+
+func TestSoap12CallFault(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope">
+			<env:Body><env:Fault>
+				<env:Code>
+					<env:Value>env:Sender</env:Value>
+					<env:Subcode><env:Value>m:MessageTimeout</env:Value></env:Subcode>
+				</env:Code>
+				<env:Reason>
+					<env:Text xml:lang="en">Sender Timeout</env:Text>
+					<env:Text xml:lang="es">Tiempo de espera del emisor</env:Text>
+				</env:Reason>
+				<env:Role>http://example.com/ping/gateway</env:Role>
+				<env:Detail><m:MaxTime xmlns:m="http://example.com/ping">P5M</m:MaxTime></env:Detail>
+			</env:Fault></env:Body>
+		</env:Envelope>`))
+	}))
+	defer ts.Close()
+
+	client := NewSoap12Client(ts.URL, false)
+	err := client.Call("", &pingRequest{}, &pingResponse{}, &SoapHeader{}, nil)
+	fault, ok := err.(*SoapFault)
+	if !ok {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, &SoapFault{})
+	}
+
+	if fault.Faultcode != "env:Sender" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", fault.Faultcode, "env:Sender")
+	}
+	if subcodes := fault.Subcodes(); len(subcodes) != 1 || subcodes[0] != "m:MessageTimeout" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", subcodes, []string{"m:MessageTimeout"})
+	}
+	if fault.Error() != "Sender Timeout" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", fault.Error(), "Sender Timeout")
+	}
+	if reason := fault.Reason("es"); reason != "Tiempo de espera del emisor" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", reason, "Tiempo de espera del emisor")
+	}
+	if fault.Faultactor != "http://example.com/ping/gateway" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", fault.Faultactor, "http://example.com/ping/gateway")
+	}
+
+	var detail struct {
+		XMLName xml.Name `xml:"http://example.com/ping MaxTime"`
+		Value   string   `xml:",chardata"`
+	}
+	err = fault.UnmarshalDetail(&detail)
+	if err != nil || detail.Value != "P5M" {
+		t.Errorf("incorrect result\ngot:  %#v, %#v\nwant: %#v", detail.Value, err, "P5M")
+	}
+}
+
+type pingSession struct {
+	XMLName xml.Name `xml:"http://example.com/ping Session"`
+	ID      string   `xml:"http://example.com/ping Id"`
+}
+
+func TestSoapCallEnvelopePrefixes(t *testing.T) {
+	// The prefix of the content is declared by the Envelope, as sent by
+	// JAX-WS or .NET services.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:tns="http://example.com/ping">
+			<soapenv:Header><tns:Session><tns:Id>s1</tns:Id></tns:Session></soapenv:Header>
+			<soapenv:Body><tns:PingResponse><Message>pong</Message></tns:PingResponse></soapenv:Body>
+		</soapenv:Envelope>`))
+	}))
+	defer ts.Close()
+
+	client := NewSoapClient(ts.URL, false)
+	response := &pingResponse{}
+	session := &pingSession{}
+	err := client.Call("", &pingRequest{}, response, &SoapHeader{RespHeader: session}, nil)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	if response.Message != "pong" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", response.Message, "pong")
+	}
+	if session.ID != "s1" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", session.ID, "s1")
+	}
+}
+
+func TestParseEnvelopeContent(t *testing.T) {
+	header, body, fault, err := parseEnvelope([]byte(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" xmlns:p="http://example.com/ping" xmlns:xsd="http://www.w3.org/2001/XMLSchema">
+		<s:Header xmlns:h="http://example.com/header"><h:Session>s1</h:Session></s:Header>
+		<s:Body><p:Ping xmlns:xsd="http://example.com/other"><p:Message>ping</p:Message></p:Ping></s:Body>
+	</s:Envelope>`), false)
+	if err != nil || fault != nil {
+		t.Fatalf("incorrect result\ngot:  %#v, %#v\nwant: %#v", err, fault, nil)
+	}
+
+	// The prefixes in scope are declared again, but for the ones declared by
+	// the element itself.
+	wantHeader := `<Session xmlns="http://example.com/header" xmlns:h="http://example.com/header" xmlns:p="http://example.com/ping" xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsd="http://www.w3.org/2001/XMLSchema">s1</Session>`
+	if header == nil || header.Content != wantHeader {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", header, wantHeader)
+	}
+	wantBody := `<Ping xmlns="http://example.com/ping" xmlns:xsd="http://example.com/other" xmlns:p="http://example.com/ping" xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><Message xmlns="http://example.com/ping">ping</Message></Ping>`
+	if body != wantBody {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", body, wantBody)
+	}
+
+	if _, _, _, err := parseEnvelope([]byte(`<Envelope xmlns="http://www.w3.org/2003/05/soap-envelope"/>`), false); err == nil {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: an error for a SOAP 1.2 envelope", err)
+	}
+}
+
+// Type of both a field and the element of the same name, like the generated
+// ones.
+type pingCredentials struct {
+	User string `xml:"http://example.com/ping User"`
+}
+
+func (c *pingCredentials) SoapElementName() xml.Name {
+	return xml.Name{Space: "http://example.com/ping", Local: "Credentials"}
+}
+
+func TestSoapCallSoapElement(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+		w.Write([]byte(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body/></soap:Envelope>`))
+	}))
+	defer ts.Close()
+
+	client := NewSoapClient(ts.URL, false)
+	header := &SoapHeader{ReqHeader: &pingCredentials{User: "header"}}
+	if err := client.Call("", &pingCredentials{User: "body"}, &pingResponse{}, header, nil); err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	// Both sent as the element, not named after the type or the field.
+	if got := strings.Count(body, `<Credentials xmlns="http://example.com/ping">`); got != 2 {
+		t.Errorf("incorrect result\ngot:  %s\nwant: %s", body, `<Credentials xmlns="http://example.com/ping">`)
+	}
+	// The caller's header is left as is.
+	if _, ok := header.ReqHeader.(*pingCredentials); !ok {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", header.ReqHeader, &pingCredentials{})
+	}
+}
+
+func TestSoapCallFault(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+			<soap:Body><soap:Fault>
+				<faultcode>soap:Server</faultcode>
+				<faultstring>Invalid credentials</faultstring>
+				<detail><InvalidCredentials xmlns="http://example.com/ping"><User>john</User></InvalidCredentials></detail>
+			</soap:Fault></soap:Body>
+		</soap:Envelope>`))
+	}))
+	defer ts.Close()
+
+	client := NewSoapClient(ts.URL, false)
+	err := client.Call("", &pingRequest{}, &pingResponse{}, &SoapHeader{}, nil)
+	fault, ok := err.(*SoapFault)
+	if !ok {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, &SoapFault{})
+	}
+
+	if fault.Faultcode != "soap:Server" || fault.Faultstring != "Invalid credentials" {
+		t.Errorf("incorrect result\ngot:  %#v", fault)
+	}
+
+	var detail struct {
+		XMLName xml.Name `xml:"http://example.com/ping InvalidCredentials"`
+		User    string   `xml:"User"`
+	}
+	err = fault.UnmarshalDetail(&detail)
+	if err != nil || detail.User != "john" {
+		t.Errorf("incorrect result\ngot:  %#v, %#v\nwant: %#v", detail.User, err, "john")
+	}
+}
+
+func TestSoapFaultDetailName(t *testing.T) {
+	tests := map[string]xml.Name{
+		`<ping:InvalidObject xmlns:ping="http://example.com/ping"><id>1</id></ping:InvalidObject>`: {Space: "http://example.com/ping", Local: "InvalidObject"},
+		`<NotFound xmlns="http://example.com/ping"><id>2</id></NotFound>`:                          {Space: "http://example.com/ping", Local: "NotFound"},
+		"": {},
+	}
+
+	for detail, want := range tests {
+		if got := (&SoapFault{Detail: detail}).DetailName(); got != want {
+			t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", got, want)
+		}
+	}
+}
+
+func TestSoapFaultDetailNamespaces(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:ns1="http://example.com/ping" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+			<soap:Body><soap:Fault>
+				<faultcode>soap:Server</faultcode>
+				<faultstring>Invalid credentials</faultstring>
+				<detail><ns1:InvalidCredentials xmlns:ns2="http://example.com/types"><ns1:User xsi:type="ns2:Name">john</ns1:User></ns1:InvalidCredentials></detail>
+			</soap:Fault></soap:Body>
+		</soap:Envelope>`))
+	}))
+	defer ts.Close()
+
+	client := NewSoapClient(ts.URL, false)
+	err := client.Call("", &pingRequest{}, &pingResponse{}, &SoapHeader{}, nil)
+	fault, ok := err.(*SoapFault)
+	if !ok {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, &SoapFault{})
+	}
+
+	var detail struct {
+		XMLName xml.Name `xml:"http://example.com/ping InvalidCredentials"`
+		User    struct {
+			Type  string `xml:"http://www.w3.org/2001/XMLSchema-instance type,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"http://example.com/ping User"`
+	}
+	err = fault.UnmarshalDetail(&detail)
+	if err != nil || detail.User.Value != "john" || detail.User.Type != "ns2:Name" {
+		t.Errorf("incorrect result\ngot:  %#v, %#v\nwant: %#v", detail.User, err, "john")
+	}
+	// The declarations of the detail content are kept for QName values.
+	if !strings.Contains(fault.Detail, `xmlns:ns2="http://example.com/types"`) {
+		t.Errorf("fault detail lost its namespace declarations: %s", fault.Detail)
+	}
+}
+
+func TestSoapCallContextCanceled(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	client := NewSoapClient(ts.URL, false)
+	err := client.CallContext(ctx, "", &pingRequest{}, &pingResponse{}, &SoapHeader{}, nil)
+	if err == nil || ctx.Err() != context.DeadlineExceeded {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", err, context.DeadlineExceeded)
+	}
+}
+
+// Returns a server sending the headers of a response and then waiting for
+// done to be closed before sending its body.
+func newStalledServer(done chan struct{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-done
+	}))
+}
+
+func TestSoapCallContextCanceledDuringBody(t *testing.T) {
+	done := make(chan struct{})
+	ts := newStalledServer(done)
+	defer ts.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := NewSoapClient(ts.URL, false)
+	configureRequest := func(req *http.Request) {
+		// Canceled once the headers of the response were received.
+		time.AfterFunc(100*time.Millisecond, cancel)
+	}
+	err := client.CallContext(ctx, "", &pingRequest{}, &pingResponse{}, &SoapHeader{}, configureRequest)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", err, context.Canceled)
+	}
+}
+
+func TestSoapCallTimeoutDuringBody(t *testing.T) {
+	done := make(chan struct{})
+	ts := newStalledServer(done)
+	defer ts.Close()
+	defer close(done)
+
+	client := NewSoapClient(ts.URL, false, WithTimeout(100*time.Millisecond))
+	err := client.Call("", &pingRequest{}, &pingResponse{}, &SoapHeader{}, nil)
+	if err == nil {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: timeout error", err)
+	}
+}
+
+const swaResponse = "--MIMEBoundary\r\n" +
+	"Content-Type: text/xml; charset=UTF-8\r\n" +
+	"Content-ID: <envelope@example.com>\r\n" +
+	"\r\n" +
+	`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">` +
+	`<soap:Body><PingResponse xmlns="http://example.com/ping"><Message>cid:scan@example.com</Message></PingResponse></soap:Body>` +
+	"</soap:Envelope>\r\n" +
+	"--MIMEBoundary\r\n" +
+	"Content-Type: image/png\r\n" +
+	"Content-Transfer-Encoding: binary\r\n" +
+	"Content-ID: <scan@example.com>\r\n" +
+	"\r\n" +
+	"\x89PNG\r\n" +
+	"--MIMEBoundary\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"Content-ID: <notes@example.com>\r\n" +
+	"\r\n" +
+	"aGVsbG8=\r\n" +
+	"--MIMEBoundary--\r\n"
+
+func TestSoapCallAttachments(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", `multipart/related; type="text/xml"; boundary=MIMEBoundary; start="<envelope@example.com>"`)
+		w.Write([]byte(swaResponse))
+	}))
+	defer ts.Close()
+
+	client := NewSoapClient(ts.URL, false)
+
+	header := &SoapHeader{}
+	response := &pingResponse{}
+	err := client.Call("", &pingRequest{}, response, header, nil)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	if len(header.Attachments) != 2 {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", len(header.Attachments), 2)
+	}
+
+	scan := header.Attachment(response.Message)
+	if scan == nil || scan.ContentType != "image/png" || string(scan.Data) != "\x89PNG" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", scan, "image/png attachment")
+	}
+
+	notes := header.Attachment("<notes@example.com>")
+	if notes == nil || string(notes.Data) != "hello" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", notes, "hello")
+	}
+}
+
+func TestSoapCallContextAttachments(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", `multipart/related; type="text/xml"; boundary=MIMEBoundary; start="<envelope@example.com>"`)
+		w.Write([]byte(swaResponse))
+	}))
+	defer ts.Close()
+
+	client := NewSoapClient(ts.URL, false)
+
+	// Without a header, as the generated operations are usually called.
+	attachments := Attachments{}
+	ctx := ContextWithAttachments(context.Background(), attachments)
+	response := &pingResponse{}
+	err := client.CallContext(ctx, "", &pingRequest{}, response, nil, nil)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	if len(attachments) != 2 {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", len(attachments), 2)
+	}
+	scan := attachments.Get(response.Message)
+	if scan == nil || scan.ContentType != "image/png" || string(scan.Data) != "\x89PNG" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", scan, "image/png attachment")
+	}
+}
@@ -0,0 +1,359 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"encoding/xml"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A global declaration of a schema: its qualified name, the package it is
+// generated in and its Go type name. Type is the qualified type of elements
+// declared with a type attribute.
+type schemaDecl struct {
+	Name           xml.Name
+	Type           xml.Name
+	Pkg            string
+	GoName         string
+	Schema         *XsdSchema
+	ComplexType    *XsdComplexType
+	Group          *XsdGroup
+	AttributeGroup *XsdAttributeGroup
+}
+
+// Pointer type of the declaration, qualified with its package.
+func (d *schemaDecl) goType() string {
+	return "*" + replaceReservedWords(d.Pkg) + "." + d.GoName
+}
+
+// Pointer type of the holder of the values of a polymorphic complex type,
+// qualified with its package.
+func (d *schemaDecl) anyType() string {
+	return "*" + replaceReservedWords(d.Pkg) + ".Any" + d.GoName
+}
+
+// Symbol table of the global declarations of a set of schemas and of the
+// messages of a WSDL, built once they are all resolved so that the templates
+// look names up instead of scanning every schema for every field.
+//
+// The first declaration of a name wins, in the order the schemas are added.
+type symbolTable struct {
+	types           declIndex
+	elements        declIndex
+	groups          declIndex
+	attributeGroups declIndex
+	messages        map[xml.Name]*WsdlMessage
+	// Complex types from which other types derive.
+	bases map[*schemaDecl]bool
+	// Namespaces of the Go names of the types and elements, by package.
+	goNames map[string]map[string]string
+	// Choices of the content models, computed once per content model.
+	choices map[*XsdCompositor]*contentChoices
+}
+
+// Declarations of a symbol space by qualified name and, for names with an
+// undeclared prefix, by local name.
+type declIndex struct {
+	names  map[xml.Name]*schemaDecl
+	locals map[string]*schemaDecl
+}
+
+func (i *declIndex) add(decl *schemaDecl) {
+	if i.names == nil {
+		i.names = make(map[xml.Name]*schemaDecl)
+		i.locals = make(map[string]*schemaDecl)
+	}
+	if _, ok := i.names[decl.Name]; !ok {
+		i.names[decl.Name] = decl
+	}
+	if _, ok := i.locals[decl.Name.Local]; !ok {
+		i.locals[decl.Name.Local] = decl
+	}
+}
+
+// Returns the declaration named name, or, for names without namespace, the
+// first one with its local name when no schema without target namespace
+// declares it.
+func (i *declIndex) get(name xml.Name) *schemaDecl {
+	if decl := i.names[name]; decl != nil || name.Space != "" {
+		return decl
+	}
+	return i.locals[name.Local]
+}
+
+func newSymbolTable() *symbolTable {
+	return &symbolTable{
+		messages: make(map[xml.Name]*WsdlMessage),
+		bases:    make(map[*schemaDecl]bool),
+		goNames:  make(map[string]map[string]string),
+		choices:  make(map[*XsdCompositor]*contentChoices),
+	}
+}
+
+// Returns the choices of the content model content, resolving its groups
+// with groups.
+func (t *symbolTable) contentChoices(groups groupResolver, content *XsdCompositor) *contentChoices {
+	c, ok := t.choices[content]
+	if !ok {
+		c = newContentChoices(groups, content)
+		t.choices[content] = c
+	}
+	return c
+}
+
+// Builds the symbol table of the schemas of a WSDL or XSD, generated in pkg,
+// and of the external ones, generated in the package named after their key.
+func newSchemasSymbolTable(pkg string, schemas []*XsdSchema, externals map[string]*XsdSchema) *symbolTable {
+	t := newSymbolTable()
+	for _, schema := range schemas {
+		t.addSchema(pkg, schema)
+	}
+
+	var keys []string
+	for key := range externals {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		t.addSchema(key, externals[key])
+	}
+
+	// Base types may be declared in any schema.
+	for _, schema := range schemas {
+		t.addDerivations(schema)
+	}
+	for _, schema := range externals {
+		t.addDerivations(schema)
+	}
+	return t
+}
+
+// Adds the global types, elements, groups and attribute groups of schema.
+func (t *symbolTable) addSchema(pkg string, schema *XsdSchema) {
+	decl := func(name, goName string) *schemaDecl {
+		return &schemaDecl{
+			Name:   xml.Name{Space: schema.TargetNamespace, Local: name},
+			Pkg:    pkg,
+			GoName: makePublic(replaceReservedWords(goName)),
+			Schema: schema,
+		}
+	}
+	// Types and elements are generated as Go types of the package.
+	typeDecl := func(name, goName string) *schemaDecl {
+		d := decl(name, goName)
+		d.GoName = t.goName(pkg, schema.TargetNamespace, d.GoName)
+		return d
+	}
+
+	for _, complexType := range schema.ComplexTypes {
+		d := typeDecl(complexType.Name, complexType.Name)
+		d.ComplexType = complexType
+		t.types.add(d)
+	}
+	for _, simpleType := range schema.SimpleType {
+		t.types.add(typeDecl(simpleType.Name, simpleType.Name))
+	}
+	for _, el := range schema.Elements {
+		d := typeDecl(el.Name, strings.Title(el.Name))
+		if el.Type != "" {
+			d.Type = schema.resolveQName(el.Type)
+		}
+		t.elements.add(d)
+	}
+	for _, group := range schema.Groups {
+		d := decl(group.Name, group.Name)
+		d.Group = group
+		t.groups.add(d)
+	}
+	for _, group := range schema.AttributeGoups {
+		if group.Name == "" {
+			continue
+		}
+		d := decl(group.Name, group.Name)
+		d.AttributeGroup = group
+		t.attributeGroups.add(d)
+	}
+}
+
+// Returns the Go name of a type or element of namespace generated in pkg.
+// The types and elements of a namespace share their Go names while the ones
+// of other namespaces get a suffix derived from their namespace, ie. the
+// Address types of urn:a and urn:b become Address and AddressB.
+func (t *symbolTable) goName(pkg, namespace, name string) string {
+	names := t.goNames[pkg]
+	if names == nil {
+		names = make(map[string]string)
+		t.goNames[pkg] = names
+	}
+
+	goName := name
+	for i := 1; ; i++ {
+		ns, ok := names[goName]
+		if !ok {
+			names[goName] = namespace
+			return goName
+		}
+		if ns == namespace {
+			return goName
+		}
+		goName = name + namespaceSuffix(namespace)
+		if i > 1 {
+			goName += strconv.Itoa(i)
+		}
+	}
+}
+
+// Returns the last word of namespace as a Go identifier, ie. B for urn:b and
+// Drawing for http://example.com/drawing.
+func namespaceSuffix(namespace string) string {
+	words := strings.FieldsFunc(namespace, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+	return makePublic(words[len(words)-1])
+}
+
+// Returns the Go name of the global type, or element, local of schema.
+func (t *symbolTable) goTypeName(schema *XsdSchema, local string, element bool) string {
+	index := t.types
+	if element {
+		index = t.elements
+	}
+	if decl := index.names[xml.Name{Space: schema.TargetNamespace, Local: local}]; decl != nil {
+		return decl.GoName
+	}
+	if element {
+		return makePublic(replaceReservedWords(strings.Title(local)))
+	}
+	return makePublic(replaceReservedWords(local))
+}
+
+// Reports whether the global type local of schema is also the Go type of the
+// global element of the same name, both sharing their Go name.
+func (t *symbolTable) typeIsElement(schema *XsdSchema, local string) bool {
+	name := xml.Name{Space: schema.TargetNamespace, Local: local}
+	typ, el := t.types.names[name], t.elements.names[name]
+	return typ != nil && el != nil && typ.GoName == el.GoName
+}
+
+// Records the base types of the complex types of schema.
+func (t *symbolTable) addDerivations(schema *XsdSchema) {
+	for _, complexType := range schema.ComplexTypes {
+		if base := t.baseType(schema, complexType); base != nil {
+			t.bases[base] = true
+		}
+	}
+}
+
+// Returns the complex type complexType of schema derives from, by extension
+// or restriction, or nil.
+func (t *symbolTable) baseType(schema *XsdSchema, complexType *XsdComplexType) *schemaDecl {
+	base := complexType.ComplexContent.Extension.Base
+	if base == "" {
+		base = complexType.ComplexContent.Restriction.Base
+	}
+	if base == "" {
+		return nil
+	}
+	decl := t.types.get(schema.resolveQName(base))
+	if decl == nil || decl.ComplexType == nil {
+		return nil
+	}
+	return decl
+}
+
+// Reports whether decl is a complex type whose values may be of a derived
+// type, ie. an abstract type or one other types derive from.
+func (t *symbolTable) polymorphic(decl *schemaDecl) bool {
+	return decl != nil && decl.ComplexType != nil && (decl.ComplexType.Abstract || t.bases[decl])
+}
+
+// Returns the declaration of complexType, a global complex type of schema,
+// or nil when an earlier declaration shadows it.
+func (t *symbolTable) complexType(schema *XsdSchema, complexType *XsdComplexType) *schemaDecl {
+	decl := t.types.get(xml.Name{Space: schema.TargetNamespace, Local: complexType.Name})
+	if decl == nil || decl.ComplexType != complexType {
+		return nil
+	}
+	return decl
+}
+
+// Returns the complex types of schema which may be named by xsi:type: the
+// polymorphic ones and the ones derived from another type.
+func (t *symbolTable) xsiTypes(schema *XsdSchema) []*schemaDecl {
+	var decls []*schemaDecl
+	for _, complexType := range schema.ComplexTypes {
+		decl := t.complexType(schema, complexType)
+		if decl != nil && (t.polymorphic(decl) || t.baseType(schema, complexType) != nil) {
+			decls = append(decls, decl)
+		}
+	}
+	return decls
+}
+
+// Adds the messages of a WSDL, declared in its target namespace.
+func (t *symbolTable) addMessages(namespace string, messages []*WsdlMessage) {
+	for _, m := range messages {
+		name := xml.Name{Space: namespace, Local: m.Name}
+		if _, ok := t.messages[name]; !ok {
+			t.messages[name] = m
+		}
+	}
+}
+
+// Returns the global type or element named name, or nil. Types are looked up
+// before elements, unless element is set.
+func (t *symbolTable) lookup(name xml.Name, element bool) *schemaDecl {
+	first, second := &t.types, &t.elements
+	if element {
+		first, second = second, first
+	}
+	if decl := first.names[name]; decl != nil {
+		return decl
+	}
+	if decl := second.names[name]; decl != nil {
+		return decl
+	}
+	if name.Space != "" {
+		return nil
+	}
+	if decl := first.locals[name.Local]; decl != nil {
+		return decl
+	}
+	return second.locals[name.Local]
+}
+
+// Returns the model group named name, or nil.
+func (t *symbolTable) group(name xml.Name) *schemaDecl {
+	return t.groups.get(name)
+}
+
+// Returns the attribute group named name, or nil.
+func (t *symbolTable) attributeGroup(name xml.Name) *schemaDecl {
+	return t.attributeGroups.get(name)
+}
+
+// Returns the complex type named by the QName base, resolved in schema, and
+// the types it derives from.
+func (t *symbolTable) ancestors(schema *XsdSchema, base string) []*schemaDecl {
+	var ancestors []*schemaDecl
+	decl := t.types.get(schema.resolveQName(base))
+	seen := make(map[*schemaDecl]bool)
+	for decl != nil && decl.ComplexType != nil && !seen[decl] {
+		seen[decl] = true
+		ancestors = append(ancestors, decl)
+		decl = t.baseType(decl.Schema, decl.ComplexType)
+	}
+	return ancestors
+}
+
+// Returns the message named name, or nil.
+func (t *symbolTable) message(name xml.Name) *WsdlMessage {
+	return t.messages[name]
+}
@@ -0,0 +1,112 @@
+package basetypes
+
+// Generated by https://github.com/hooklift/gowsdl
+// Do not modify
+// Copyright (c) 2015, Hooklift. All rights reserved.
+import (
+	"encoding/xml"
+	"time"
+
+	gowsdl "github.com/hooklift/gowsdl/generator"
+)
+
+// against "unused imports"
+var _ time.Time
+var _ xml.Name
+var _ gowsdl.Base64Binary
+
+//ComplexTypeLocal
+
+type GetAccount struct {
+	XMLName xml.Name `xml:"http://example.com/accounts GetAccount"`
+
+	//AttributeGroups
+
+	//Particles
+
+	//type
+
+	//basetype
+	Id string `xml:"http://example.com/accounts Id,omitempty"`
+
+	//Attributes
+
+}
+
+//Choices
+
+//ParticlesTypes
+
+//ElementsTypes
+
+//ComplexTypeLocal
+
+type GetAccountResponse struct {
+	XMLName xml.Name `xml:"http://example.com/accounts GetAccountResponse"`
+
+	//AttributeGroups
+
+	//Particles
+
+	//type
+
+	//basetype
+	Owner string `xml:"http://example.com/accounts Owner,omitempty"`
+
+	//Attributes
+
+}
+
+//Choices
+
+//ParticlesTypes
+
+//ElementsTypes
+
+//ComplexTypeLocal
+
+type InvalidId struct {
+	XMLName xml.Name `xml:"http://example.com/accounts InvalidId"`
+
+	//AttributeGroups
+
+	//Particles
+
+	//type
+
+	//basetype
+	Id string `xml:"http://example.com/accounts Id,omitempty"`
+
+	//Attributes
+
+}
+
+//Choices
+
+//ParticlesTypes
+
+//ElementsTypes
+
+//ComplexTypeLocal
+
+type NotFound struct {
+	XMLName xml.Name `xml:"http://example.com/accounts NotFound"`
+
+	//AttributeGroups
+
+	//Particles
+
+	//type
+
+	//basetype
+	Id string `xml:"http://example.com/accounts Id,omitempty"`
+
+	//Attributes
+
+}
+
+//Choices
+
+//ParticlesTypes
+
+//ElementsTypes
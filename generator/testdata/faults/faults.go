@@ -0,0 +1,131 @@
+package faults
+
+// Generated by https://github.com/hooklift/gowsdl
+// Do not modify
+// Copyright (c) 2015, Hooklift. All rights reserved.
+import (
+	"context"
+	"net/http"
+
+	"encoding/xml"
+	"time"
+
+	"github.com/hooklift/gowsdl/generator/testdata/faults/basetypes"
+
+	gowsdl "github.com/hooklift/gowsdl/generator"
+)
+
+// against "unused imports"
+var _ time.Time
+var _ xml.Name
+
+type Accounts struct {
+	client *gowsdl.SoapClient
+}
+
+func NewAccounts(url string, tls bool, opts ...gowsdl.ClientOption) *Accounts {
+	if url == "" {
+		url = "http://localhost:8080/accounts"
+	}
+
+	client := gowsdl.NewSoapClient(url, tls, opts...)
+
+	return &Accounts{
+		client: client,
+	}
+}
+
+// AccountsInterface lists the operations of Accounts, ie. to mock it
+// in tests.
+type AccountsInterface interface {
+	GetAccount(request *basetypes.GetAccount, header *gowsdl.SoapHeader, configureRequest func(*http.Request)) (*basetypes.GetAccountResponse, error)
+	GetAccountContext(ctx context.Context, request *basetypes.GetAccount, header *gowsdl.SoapHeader, configureRequest func(*http.Request)) (*basetypes.GetAccountResponse, error)
+}
+
+var _ AccountsInterface = (*Accounts)(nil)
+
+// InvalidIdFault is returned when the service answers with a fault
+// whose detail is a basetypes.InvalidId.
+type InvalidIdFault struct {
+	*gowsdl.SoapFault
+	FaultDetail *basetypes.InvalidId
+}
+
+func (f *InvalidIdFault) Unwrap() error {
+	return f.SoapFault
+}
+
+func (f *InvalidIdFault) SoapFaultDetail() (*gowsdl.SoapFault, interface{}) {
+	return f.SoapFault, f.FaultDetail
+}
+
+func newInvalidIdFault(fault *gowsdl.SoapFault) *InvalidIdFault {
+	if name := fault.DetailName(); name.Local != "InvalidId" || name.Space != "http://example.com/accounts" {
+		return nil
+	}
+	detail := &basetypes.InvalidId{}
+	if err := fault.UnmarshalDetail(detail); err != nil {
+		return nil
+	}
+	return &InvalidIdFault{SoapFault: fault, FaultDetail: detail}
+}
+
+// NotFoundFault is returned when the service answers with a fault
+// whose detail is a basetypes.NotFound.
+type NotFoundFault struct {
+	*gowsdl.SoapFault
+	FaultDetail *basetypes.NotFound
+}
+
+func (f *NotFoundFault) Unwrap() error {
+	return f.SoapFault
+}
+
+func (f *NotFoundFault) SoapFaultDetail() (*gowsdl.SoapFault, interface{}) {
+	return f.SoapFault, f.FaultDetail
+}
+
+func newNotFoundFault(fault *gowsdl.SoapFault) *NotFoundFault {
+	if name := fault.DetailName(); name.Local != "NotFound" || name.Space != "http://example.com/accounts" {
+		return nil
+	}
+	detail := &basetypes.NotFound{}
+	if err := fault.UnmarshalDetail(detail); err != nil {
+		return nil
+	}
+	return &NotFoundFault{SoapFault: fault, FaultDetail: detail}
+}
+
+// Error can be either of the following types:
+//
+//   - InvalidId (*InvalidIdFault)
+//   - NotFound (*NotFoundFault)
+
+func (service *Accounts) GetAccount(request *basetypes.GetAccount, header *gowsdl.SoapHeader, configureRequest func(*http.Request)) (*basetypes.GetAccountResponse, error) {
+	return service.GetAccountContext(context.Background(), request, header, configureRequest)
+}
+
+// GetAccountContext is like GetAccount but the call is bound to ctx.
+func (service *Accounts) GetAccountContext(ctx context.Context, request *basetypes.GetAccount, header *gowsdl.SoapHeader, configureRequest func(*http.Request)) (*basetypes.GetAccountResponse, error) {
+	response := &basetypes.GetAccountResponse{}
+
+	err := service.client.CallContext(ctx, "http://example.com/accounts/GetAccount", request, response, header, configureRequest)
+	if err != nil {
+
+		if fault, ok := err.(*gowsdl.SoapFault); ok {
+
+			if typedFault := newInvalidIdFault(fault); typedFault != nil {
+				return nil, typedFault
+			}
+
+			if typedFault := newNotFoundFault(fault); typedFault != nil {
+				return nil, typedFault
+			}
+
+		}
+
+		return nil, err
+	}
+
+	return response, nil
+}
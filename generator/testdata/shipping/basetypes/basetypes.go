@@ -0,0 +1,135 @@
+package basetypes
+
+// Generated by https://github.com/hooklift/gowsdl
+// Do not modify
+// Copyright (c) 2015, Hooklift. All rights reserved.
+import (
+	"encoding/xml"
+	"time"
+
+	gowsdl "github.com/hooklift/gowsdl/generator"
+)
+
+// against "unused imports"
+var _ time.Time
+var _ xml.Name
+var _ gowsdl.Base64Binary
+
+//ComplexTypeGlobal
+
+type Address struct {
+
+	//AttributeGroups
+
+	//Particles
+
+	//type
+
+	//basetype
+	Street string `xml:"http://example.com/shipping Street,omitempty"`
+
+	//type
+
+	//basetype
+	City string `xml:"http://example.com/shipping City,omitempty"`
+
+	//Attributes
+
+}
+
+// SoapElementName returns the name of the element of type Address, which
+// Address is sent as when it isn't the value of a field.
+func (t *Address) SoapElementName() xml.Name {
+	return xml.Name{Space: "http://example.com/shipping", Local: "Address"}
+}
+
+//Choices
+
+//ParticlesTypes
+
+//ElementsTypes
+
+//ElementsTypes
+
+//ELEMENT TYPE
+
+//ComplexTypeLocal
+
+type Ship struct {
+	XMLName xml.Name `xml:"http://example.com/shipping Ship"`
+
+	//AttributeGroups
+
+	//Particles
+
+	//type
+
+	//else
+
+	From *Address `xml:"http://example.com/shipping From,omitempty"`
+
+	//type
+
+	//else
+
+	To *Address `xml:"http://example.com/shipping To,omitempty"`
+
+	//Attributes
+
+}
+
+//Choices
+
+//ParticlesTypes
+
+//ElementsTypes
+
+//ElementsTypes
+
+//ComplexTypeLocal
+
+type ShipResponse struct {
+	XMLName xml.Name `xml:"http://example.com/shipping ShipResponse"`
+
+	//AttributeGroups
+
+	//Particles
+
+	//type
+
+	//basetype
+	Tracking string `xml:"http://example.com/shipping Tracking,omitempty"`
+
+	//Attributes
+
+}
+
+//Choices
+
+//ParticlesTypes
+
+//ElementsTypes
+
+//ComplexTypeLocal
+
+type CheckAddressResponse struct {
+	XMLName xml.Name `xml:"http://example.com/shipping CheckAddressResponse"`
+
+	//AttributeGroups
+
+	//Particles
+
+	//type
+
+	//basetype
+	Valid bool `xml:"http://example.com/shipping Valid,omitempty"`
+
+	//Attributes
+
+}
+
+//Choices
+
+//ParticlesTypes
+
+//ElementsTypes
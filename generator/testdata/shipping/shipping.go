@@ -0,0 +1,138 @@
+package shipping
+
+// Generated by https://github.com/hooklift/gowsdl
+// Do not modify
+// Copyright (c) 2015, Hooklift. All rights reserved.
+import (
+	"context"
+	"net/http"
+
+	"encoding/xml"
+	"time"
+
+	"github.com/hooklift/gowsdl/generator/testdata/shipping/basetypes"
+
+	gowsdl "github.com/hooklift/gowsdl/generator"
+)
+
+// against "unused imports"
+var _ time.Time
+var _ xml.Name
+
+type Shipping struct {
+	client *gowsdl.SoapClient
+}
+
+func NewShipping(url string, tls bool, opts ...gowsdl.ClientOption) *Shipping {
+	if url == "" {
+		url = "http://localhost:8080/shipping"
+	}
+
+	client := gowsdl.NewSoapClient(url, tls, opts...)
+
+	return &Shipping{
+		client: client,
+	}
+}
+
+// ShippingInterface lists the operations of Shipping, ie. to mock it
+// in tests.
+type ShippingInterface interface {
+	Ship(request *basetypes.Ship, header *gowsdl.SoapHeader, configureRequest func(*http.Request)) (*basetypes.ShipResponse, error)
+	ShipContext(ctx context.Context, request *basetypes.Ship, header *gowsdl.SoapHeader, configureRequest func(*http.Request)) (*basetypes.ShipResponse, error)
+
+	CheckAddress(request *basetypes.Address, header *gowsdl.SoapHeader, configureRequest func(*http.Request)) (*basetypes.CheckAddressResponse, error)
+	CheckAddressContext(ctx context.Context, request *basetypes.Address, header *gowsdl.SoapHeader, configureRequest func(*http.Request)) (*basetypes.CheckAddressResponse, error)
+}
+
+var _ ShippingInterface = (*Shipping)(nil)
+
+func (service *Shipping) Ship(request *basetypes.Ship, header *gowsdl.SoapHeader, configureRequest func(*http.Request)) (*basetypes.ShipResponse, error) {
+	return service.ShipContext(context.Background(), request, header, configureRequest)
+}
+
+// ShipContext is like Ship but the call is bound to ctx.
+func (service *Shipping) ShipContext(ctx context.Context, request *basetypes.Ship, header *gowsdl.SoapHeader, configureRequest func(*http.Request)) (*basetypes.ShipResponse, error) {
+	response := &basetypes.ShipResponse{}
+
+	err := service.client.CallContext(ctx, "http://example.com/shipping/Ship", request, response, header, configureRequest)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (service *Shipping) CheckAddress(request *basetypes.Address, header *gowsdl.SoapHeader, configureRequest func(*http.Request)) (*basetypes.CheckAddressResponse, error) {
+	return service.CheckAddressContext(context.Background(), request, header, configureRequest)
+}
+
+// CheckAddressContext is like CheckAddress but the call is bound to ctx.
+func (service *Shipping) CheckAddressContext(ctx context.Context, request *basetypes.Address, header *gowsdl.SoapHeader, configureRequest func(*http.Request)) (*basetypes.CheckAddressResponse, error) {
+	response := &basetypes.CheckAddressResponse{}
+
+	err := service.client.CallContext(ctx, "http://example.com/shipping/CheckAddress", request, response, header, configureRequest)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// ShippingServer is implemented by the services of the Shipping port type,
+// see NewShippingHandler. Errors other than *gowsdl.SoapFault and the
+// typed faults of the operations are sent as server faults.
+type ShippingServer interface {
+	Ship(ctx context.Context, request *basetypes.Ship) (*basetypes.ShipResponse, error)
+
+	CheckAddress(ctx context.Context, request *basetypes.Address) (*basetypes.CheckAddressResponse, error)
+}
+
+// NewShippingHandler returns an http.Handler serving the operations of the
+// Shipping port type with service.
+func NewShippingHandler(service ShippingServer) http.Handler {
+	return gowsdl.NewSoapHandler([]*gowsdl.SoapOperation{
+
+		{
+			Name:    "Ship",
+			Action:  "http://example.com/shipping/Ship",
+			Element: xml.Name{Space: "http://example.com/shipping", Local: "Ship"},
+			Serve: func(ctx context.Context, header, body string) (interface{}, interface{}, error) {
+
+				request := &basetypes.Ship{}
+				if err := gowsdl.UnmarshalContent(body, request); err != nil {
+					return nil, nil, gowsdl.NewClientFault(err)
+				}
+				if err := gowsdl.Validate(request); err != nil {
+					return nil, nil, gowsdl.NewClientFault(err)
+				}
+
+				response, err := service.Ship(ctx, request)
+				return response, nil, err
+
+			},
+		},
+
+		{
+			Name:    "CheckAddress",
+			Action:  "http://example.com/shipping/CheckAddress",
+			Element: xml.Name{Space: "http://example.com/shipping", Local: "Address"},
+			Serve: func(ctx context.Context, header, body string) (interface{}, interface{}, error) {
+
+				request := &basetypes.Address{}
+				if err := gowsdl.UnmarshalContent(body, request); err != nil {
+					return nil, nil, gowsdl.NewClientFault(err)
+				}
+				if err := gowsdl.Validate(request); err != nil {
+					return nil, nil, gowsdl.NewClientFault(err)
+				}
+
+				response, err := service.CheckAddress(ctx, request)
+				return response, nil, err
+
+			},
+		},
+	})
+}
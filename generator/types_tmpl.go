@@ -6,7 +6,7 @@ package generator
 var typesTmpl = `
 {{define "SimpleType"}}
 	//SimpleType
-	{{$type := replaceReservedWords .Name | makePublic}}
+	{{$type := typeName .Name}}
 	{{if processSimpleType $type}}
 		{{if .Doc}} {{.Doc | comment}} {{end}}
 		{{if .Restriction.Base}}
@@ -39,23 +39,25 @@ var typesTmpl = `
 {{define "Attributes"}}
 	//Attributes
 	{{range .}}
-		{{if .Doc}} {{.Doc | comment}} {{end}}
-		{{if .Type}}
-			//type
-			{{$attributeType := findType .Type }}
-			{{ replaceReservedWords .Name | makePublic}} {{$attributeType}} ` + "`" + `xml:"{{.Name}},attr,omitempty"` + "`" + `
-		{{else if .SimpleType}}
-			{{ if .SimpleType.Restriction.Base }}
-				//restriction
-				{{$attributeType := findType .SimpleType.Restriction.Base }}
-				{{ replaceReservedWords .Name | makePublic}} {{$attributeType}} ` + "`" + `xml:"{{.Name}},attr,omitempty"` + "`" + `
-			{{else}}
-				//uniontype
-				{{.SimpleType.UnionType.MemberType | comment}}
-				{{ replaceReservedWords .Name | makePublic}} string ` + "`" + `xml:"{{.Name}},attr,omitempty"` + "`" + `
+		{{if ne .Use "prohibited"}}
+			{{if .Doc}} {{.Doc | comment}} {{end}}
+			{{if .Type}}
+				//type
+				{{$attributeType := findType .Type }}
+				{{ replaceReservedWords .Name | makePublic}} {{$attributeType}} ` + "`" + `xml:"{{attributeName .Name .Form}},attr,omitempty"` + "`" + `
+			{{else if .SimpleType}}
+				{{ if .SimpleType.Restriction.Base }}
+					//restriction
+					{{$attributeType := findType .SimpleType.Restriction.Base }}
+					{{ replaceReservedWords .Name | makePublic}} {{$attributeType}} ` + "`" + `xml:"{{attributeName .Name .Form}},attr,omitempty"` + "`" + `
+				{{else}}
+					//uniontype
+					{{.SimpleType.UnionType.MemberType | comment}}
+					{{ replaceReservedWords .Name | makePublic}} string ` + "`" + `xml:"{{attributeName .Name .Form}},attr,omitempty"` + "`" + `
+				{{end}}
+			{{ else }}
+				{{ replaceReservedWords .Name | makePublic}} string ` + "`" + `xml:"{{attributeName .Name .Form}},attr,omitempty"` + "`" + `
 			{{end}}
-		{{ else }}
-			{{ replaceReservedWords .Name | makePublic}} string ` + "`" + `xml:"{{.Name}},attr,omitempty"` + "`" + `
 		{{end}}
 	{{end}}
 {{end}}
@@ -63,24 +65,131 @@ var typesTmpl = `
 {{define "AttributeGroups"}}
 	//AttributeGroups
 	{{range .}}
-		{{if .Doc}} {{.Doc | comment}} {{end}}
 		{{if .Ref}}
-			{{$attributeType := findType .Ref }}
-			{{ replaceReservedWords .Ref | makePublic}} {{$attributeType}}
-		{{else}}
-			{{$name := replaceReservedWords .Name | makePublic}}
-			{{if processSimpleType $name}}
-				type {{$name}} struct {
-					{{if targetNamespace}}
-						XMLName xml.Name ` + "`xml:\"{{targetNamespace}} {{.Name}}\"`" + `
-					{{else}}
-						XMLName xml.Name ` + "`xml:\"{{.Name}}\"`" + `
-					{{end}}
+			{{with findAttributeGroup .Ref}}
+				{{pushSchemaScope .Schema}}
+				//AttributeGroup {{.Name.Local}}
+				{{if .AttributeGroup.Doc}} {{.AttributeGroup.Doc | comment}} {{end}}
+				{{template "Attributes" .AttributeGroup.Attributes}}
+				{{template "AttributeGroups" .AttributeGroup.AttributeGroups}}
+				{{popSchemaScope}}
+			{{end}}
+		{{end}}
+	{{end}}
+{{end}}
 
-					{{template "Attributes" .Attributes}}
-				}
+{{define "Group"}}
+	//Group
+	{{$parent := .ParentName}}
+	{{$choices := .Choices}}
+	{{with .Value}}
+		{{$repeated := or $.Repeated (isArrayElement .MaxOccurs)}}
+		{{with findGroup .Ref}}
+			{{pushSchemaScope .Schema}}
+			//Group {{.Name.Local}}
+			{{if .Group.Doc}} {{.Group.Doc | comment}} {{end}}
+			{{with .Group.Content}}
+				{{template "Particles" dictValues "ParentName" $parent "Value" . "Choices" $choices "Repeated" $repeated}}
 			{{end}}
+			{{popSchemaScope}}
+		{{end}}
+	{{end}}
+{{end}}
+
+{{define "GroupTypes"}}
+	//GroupTypes
+	{{$parent := .ParentName}}
+	{{with findGroup .Value.Ref}}
+		{{pushSchemaScope .Schema}}
+		{{with .Group.Content}}
+			{{template "ParticlesTypes" dictValues "ParentName" $parent "Value" .}}
 		{{end}}
+		{{popSchemaScope}}
+	{{end}}
+{{end}}
+
+{{define "Particles"}}
+	//Particles
+	{{$parent := .ParentName}}
+	{{$choices := .Choices}}
+	{{$repeated := .Repeated}}
+	{{if not .Item}}
+		{{$repeated = or .Repeated (isArrayElement .Value.MaxOccurs)}}
+	{{end}}
+	{{if and (not .Item) ($choices.IsOrdered .Value)}}
+		{{$choices.OrderedField}} []*{{$parent}}{{$choices.OrderedField}} ` + "`" + `xml:",any"` + "`" + `
+	{{else}}
+		{{range .Value.Particles}}
+			{{if .Element}}
+				{{template "Element" dictValues "Value" .Element "Repeated" $repeated}}
+			{{else if .Compositor}}
+				{{template "Particles" dictValues "ParentName" $parent "Value" .Compositor "Choices" $choices "Repeated" $repeated}}
+			{{else if .Group}}
+				{{template "Group" dictValues "ParentName" $parent "Value" .Group "Choices" $choices "Repeated" $repeated}}
+			{{end}}
+		{{end}}
+	{{end}}
+{{end}}
+
+{{define "ParticlesTypes"}}
+	//ParticlesTypes
+	{{$parent := .ParentName}}
+	{{range .Value.Particles}}
+		{{if .Element}}
+			{{template "ElementsTypes" dictValues "ParentName" $parent "Values" (elementList .Element)}}
+		{{else if .Compositor}}
+			{{template "ParticlesTypes" dictValues "ParentName" $parent "Value" .Compositor}}
+		{{else if .Group}}
+			{{template "GroupTypes" dictValues "ParentName" $parent "Value" .Group}}
+		{{end}}
+	{{end}}
+{{end}}
+
+{{define "Choices"}}
+	//Choices
+	{{$name := .Name}}
+	{{$choices := .Choices}}
+	{{if $choices.Exclusive}}
+		// Validate returns a *gowsdl.ChoiceError when more than one branch of a
+		// choice of {{$name}} is set.
+		func (t *{{$name}}) Validate() error {
+			{{range $choices.Exclusive}}
+				if err := gowsdl.ValidateChoice({{printf "%q" $name}},
+					{{range $choices.Branches .}}
+						gowsdl.ChoiceBranch{Name: {{printf "%q" .Name}}, Fields: []interface{}{ {{- range $i, $field := .Fields}}{{if $i}}, {{end}}t.{{$field}}{{end -}} }},
+					{{end}}
+				); err != nil {
+					return err
+				}
+			{{end}}
+			return nil
+		}
+	{{end}}
+	{{with $choices.Ordered}}
+		{{$item := print $name $choices.OrderedField}}
+		// {{$item}} is an occurrence of the repeating choice of {{$name}},
+		// only one of its branches may be set.
+		type {{$item}} struct {
+			{{template "Particles" dictValues "ParentName" $name "Value" . "Choices" $choices "Repeated" false "Item" true}}
+		}
+
+		func (c *{{$item}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+			return gowsdl.MarshalChoice(e, c)
+		}
+
+		func (c *{{$item}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+			return gowsdl.UnmarshalChoice(d, start, c)
+		}
+
+		// Validate returns a *gowsdl.ChoiceError when more than one branch of c
+		// is set.
+		func (c *{{$item}}) Validate() error {
+			return gowsdl.ValidateChoice({{printf "%q" $item}},
+				{{range $choices.Branches .}}
+					gowsdl.ChoiceBranch{Name: {{printf "%q" .Name}}, Fields: []interface{}{ {{- range $i, $field := .Fields}}{{if $i}}, {{end}}c.{{$field}}{{end -}} }},
+				{{end}}
+			)
+		}
 	{{end}}
 {{end}}
 
@@ -106,15 +215,12 @@ var typesTmpl = `
 	{{/* $parent := .ParentName */}}
 	{{with .Value}}
 		{{/* $name := title .Name | print $parent | replaceReservedWords | makePublic */}}
-		{{ $name := replaceReservedWords .Name | makePublic }}
+		{{ $name := typeName .Name }}
 		{{ if processComplexType $name }}
 			{{if .Doc}} {{.Doc | comment}} {{end}}
+			{{/* Fields name the elements of named types, which have no
+			     XMLName, even the one of the element of the same name. */}}
 			type {{$name}} struct {
-				{{if targetNamespace}}
-					XMLName xml.Name ` + "`xml:\"{{targetNamespace}} {{.Name}}\"`" + `
-				{{else}}
-					XMLName xml.Name ` + "`xml:\"{{.Name}}\"`" + `
-				{{end}}
 				{{ if .Any }}
 					Any interface{}
 				{{end}}
@@ -130,33 +236,89 @@ var typesTmpl = `
 							{{ if eq $baseType "*interface{}"}}
 								//{{$baseType}}
 							{{else}}
-								{{$baseType}}
+								{{replaceStar $baseType}}
 							{{end}}
 						{{end}}
 
-						{{template "Elements" dictValues "ParentName" $name "Values" .Extension.Sequence}}
+						{{with .Extension.Content}}
+							{{template "Particles" dictValues "ParentName" $name "Value" . "Choices" (contentChoices .) "Repeated" false}}
+						{{end}}
 						{{template "Attributes" .Extension.Attributes}}
+						{{template "AttributeGroups" .Extension.AttributeGroups}}
+					{{end}}
+				{{else if ne .ComplexContent.Restriction.Base ""}}
+					{{with .ComplexContent.Restriction}}
+						{{if not (isSoapArrayBase .Base)}}
+							//ComplexContent restriction
+							{{template "AttributeGroups" .AttributeGroups}}
+							{{with .Content}}
+								{{template "Particles" dictValues "ParentName" $name "Value" . "Choices" (contentChoices .) "Repeated" false}}
+							{{end}}
+							{{template "Attributes" .Attributes}}
+						{{end}}
 					{{end}}
 				{{else if ne .SimpleContent.Extension.Base ""}}
 					{{template "SimpleContent" .SimpleContent}}
 				{{else}}
 					{{template "AttributeGroups" .AttributeGoups}}
-					{{template "Elements" dictValues "ParentName" $name "Values" .Sequence}}
-					{{template "Elements" dictValues "ParentName" $name "Values" .SubSequence}}
-					{{template "Elements" dictValues "ParentName" $name "Values" .Choice}}
-					{{template "Elements" dictValues "ParentName" $name "Values" .All}}
+					{{with .Content}}
+						{{template "Particles" dictValues "ParentName" $name "Value" . "Choices" (contentChoices .) "Repeated" false}}
+					{{end}}
 					{{template "Attributes" .Attributes}}
 				{{end}}
 			}
+			{{if typeIsElement .Name}}
+				// SoapElementName returns the name of the element of type {{$name}}, which
+				// {{$name}} is sent as when it isn't the value of a field.
+				func (t *{{$name}}) SoapElementName() xml.Name {
+					return xml.Name{Space: "{{targetNamespace}}", Local: "{{.Name}}"}
+				}
+			{{end}}
+			{{if isPolymorphic .}}
+				// {{$name}}Interface is implemented by {{$name}}{{if .Abstract}}, which is abstract,{{end}} and
+				// by the types derived from it.
+				type {{$name}}Interface interface {
+					Is{{$name}}()
+				}
+
+				// Is{{$name}} marks {{$name}} as a {{$name}}Interface.
+				func (t *{{$name}}) Is{{$name}}() {}
+
+				// Any{{$name}} holds the value of an element of type {{$name}}, which may
+				// be of a type derived from it: it is marshalled with the xsi:type of
+				// its value and unmarshalled into the type named by its xsi:type.
+				type Any{{$name}} struct {
+					Value {{$name}}Interface
+				}
+
+				func (a *Any{{$name}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+					return gowsdl.MarshalXsiType(e, start, a.Value)
+				}
+
+				func (a *Any{{$name}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+					return gowsdl.UnmarshalXsiType(d, start, &a.Value, func() interface{} { return new({{$name}}) })
+				}
+			{{end}}
 			{{if ne .ComplexContent.Extension.Base ""}}
-				{{template "ElementsTypes" dictValues "ParentName" $name "Values" .ComplexContent.Extension.Sequence}}
-			{{else if ne .SimpleContent.Extension.Base ""}}
-				{{template "ElementsTypes" dictValues "ParentName" $name "Values" .SimpleContent.Extension.Sequence}}
-			{{else}}
-				{{template "ElementsTypes" dictValues "ParentName" $name "Values" .Sequence}}
-				{{template "ElementsTypes" dictValues "ParentName" $name "Values" .SubSequence}}
-				{{template "ElementsTypes" dictValues "ParentName" $name "Values" .Choice}}
-				{{template "ElementsTypes" dictValues "ParentName" $name "Values" .All}}
+				{{with .ComplexContent.Extension.Content}}
+					{{template "Choices" dictValues "Name" $name "Choices" (contentChoices .)}}
+					{{template "ParticlesTypes" dictValues "ParentName" $name "Value" .}}
+				{{end}}
+			{{else if ne .ComplexContent.Restriction.Base ""}}
+				{{with .ComplexContent.Restriction.Content}}
+					{{template "Choices" dictValues "Name" $name "Choices" (contentChoices .)}}
+					{{template "ParticlesTypes" dictValues "ParentName" $name "Value" .}}
+				{{end}}
+				{{range ancestors .ComplexContent.Restriction.Base}}
+					// Is{{.GoName}} marks {{$name}}, derived from {{.GoName}} by restriction, as
+					// a {{.GoName}}Interface.
+					func (t *{{$name}}) Is{{.GoName}}() {}
+				{{end}}
+			{{else if eq .SimpleContent.Extension.Base ""}}
+				{{with .Content}}
+					{{template "Choices" dictValues "Name" $name "Choices" (contentChoices .)}}
+					{{template "ParticlesTypes" dictValues "ParentName" $name "Value" .}}
+				{{end}}
 			{{end}}
 		{{ end }}
 	{{ end }}
@@ -168,10 +330,13 @@ var typesTmpl = `
 	{{with .Value}}
 		{{/* $name := title .Name | print $parent | replaceReservedWords | makePublic */}}
 		{{ $name := title .Name | replaceReservedWords | makePublic }}
+		{{ if not $.Local }}{{ $name = elementTypeName .Name }}{{ end }}
 		{{ if processComplexType $name }}
 			{{if .Doc}} {{.Doc | comment}} {{end}}
 			type {{ $name }} struct {
-				{{if targetNamespace}}
+				{{if $.Local}}
+					XMLName xml.Name ` + "`xml:\"{{elementName .Name .Form}}\"`" + `
+				{{else if targetNamespace}}
 					XMLName xml.Name ` + "`xml:\"{{targetNamespace}} {{.Name}}\"`" + `
 				{{else}}
 					XMLName xml.Name ` + "`xml:\"{{.Name}}\"`" + `
@@ -192,35 +357,59 @@ var typesTmpl = `
 								{{ if eq $baseType "*interface{}"}}
 									//{{$baseType}}
 								{{else}}
-									{{$baseType}}
+									{{replaceStar $baseType}}
 								{{end}}
 							{{end}}
 
-							{{template "Elements" dictValues "ParentName" $name "Values" .Extension.Sequence}}
+							{{with .Extension.Content}}
+								{{template "Particles" dictValues "ParentName" $name "Value" . "Choices" (contentChoices .) "Repeated" false}}
+							{{end}}
 							{{template "Attributes" .Extension.Attributes}}
+							{{template "AttributeGroups" .Extension.AttributeGroups}}
+						{{end}}
+					{{else if ne .ComplexContent.Restriction.Base ""}}
+						{{with .ComplexContent.Restriction}}
+							{{if not (isSoapArrayBase .Base)}}
+								//ComplexContent restriction
+								{{template "AttributeGroups" .AttributeGroups}}
+								{{with .Content}}
+									{{template "Particles" dictValues "ParentName" $name "Value" . "Choices" (contentChoices .) "Repeated" false}}
+								{{end}}
+								{{template "Attributes" .Attributes}}
+							{{end}}
 						{{end}}
 					{{else if ne .SimpleContent.Extension.Base ""}}
 						{{template "SimpleContent" .SimpleContent}}
 					{{else}}
 						{{template "AttributeGroups" .AttributeGoups}}
-						{{template "Elements" dictValues "ParentName" $name "Values" .Sequence}}
-						{{template "Elements" dictValues "ParentName" $name "Values" .SubSequence}}
-						{{template "Elements" dictValues "ParentName" $name "Values" .Choice}}
-						{{template "Elements" dictValues "ParentName" $name "Values" .All}}
+						{{with .Content}}
+							{{template "Particles" dictValues "ParentName" $name "Value" . "Choices" (contentChoices .) "Repeated" false}}
+						{{end}}
 						{{template "Attributes" .Attributes}}
 					{{end}}
 				{{end}}
 			}
 			{{with .ComplexType}}
 				{{if ne .ComplexContent.Extension.Base ""}}
-					{{template "ElementsTypes" dictValues "ParentName" $name "Values" .ComplexContent.Extension.Sequence}}
-				{{else if ne .SimpleContent.Extension.Base ""}}
-					{{template "ElementsTypes" dictValues "ParentName" $name "Values" .SimpleContent.Extension.Sequence}}
-				{{else}}
-					{{template "ElementsTypes" dictValues "ParentName" $name "Values" .Sequence}}
-					{{template "ElementsTypes" dictValues "ParentName" $name "Values" .SubSequence}}
-					{{template "ElementsTypes" dictValues "ParentName" $name "Values" .Choice}}
-					{{template "ElementsTypes" dictValues "ParentName" $name "Values" .All}}
+					{{with .ComplexContent.Extension.Content}}
+						{{template "Choices" dictValues "Name" $name "Choices" (contentChoices .)}}
+						{{template "ParticlesTypes" dictValues "ParentName" $name "Value" .}}
+					{{end}}
+				{{else if ne .ComplexContent.Restriction.Base ""}}
+					{{with .ComplexContent.Restriction.Content}}
+						{{template "Choices" dictValues "Name" $name "Choices" (contentChoices .)}}
+						{{template "ParticlesTypes" dictValues "ParentName" $name "Value" .}}
+					{{end}}
+					{{range ancestors .ComplexContent.Restriction.Base}}
+						// Is{{.GoName}} marks {{$name}}, derived from {{.GoName}} by restriction, as
+						// a {{.GoName}}Interface.
+						func (t *{{$name}}) Is{{.GoName}}() {}
+					{{end}}
+				{{else if eq .SimpleContent.Extension.Base ""}}
+					{{with .Content}}
+						{{template "Choices" dictValues "Name" $name "Choices" (contentChoices .)}}
+						{{template "ParticlesTypes" dictValues "ParentName" $name "Value" .}}
+					{{end}}
 				{{end}}
 			{{end}}
 		{{ end }}
@@ -234,7 +423,7 @@ var typesTmpl = `
 		{{if not .Ref}}
 			{{if not .SimpleType}}
 				{{ if not .Type }}
-					{{template "ComplexTypeLocal" dictValues "ParentName" "" "Value" .}}
+					{{template "ComplexTypeLocal" dictValues "ParentName" "" "Value" . "Local" true}}
 				{{end}}
 			{{end}}
 		{{end}}
@@ -245,41 +434,50 @@ var typesTmpl = `
 	//Elements
 	{{/* $parent := .ParentName */}}
 	{{range .Values}}
-		{{if .Doc}} {{.Doc | comment}} {{end}}
-		{{if not .Type}}
-			//not type
-			{{if .SimpleType}}
-				//simple
-				{{if .SimpleType.Doc}} {{.SimpleType.Doc | comment}} {{end}}
-				{{ replaceReservedWords .Name | makePublic}} {{toGoType .SimpleType.Restriction.Base}} ` + "`" + `xml:"{{.Name}},omitempty"` + "`" + `
-			{{else if .Ref}}
-				//ref
-				{{$elementType := findType .Ref }}
-				{{if isArrayElement .MaxOccurs }}//MAX OCCUR {{ .MaxOccurs }}{{end}}
-				{{ if .Name }}
-					{{replaceReservedWords .Ref | makePublic}} {{if isArrayElement .MaxOccurs }}[]{{end}}{{ $elementType }} ` + "`" + `xml:"{{.Name}},omitempty"` + "`" + `
-				{{else}}
-					{{replaceReservedWords .Ref | makePublic}} {{if isArrayElement .MaxOccurs }}[]{{end}}{{ $elementType }}
-				{{end}}
+		{{template "Element" dictValues "Value" . "Repeated" false}}
+	{{end}}
+{{end}}
+
+{{define "Element"}}
+	{{with .Value}}
+	{{$array := or $.Repeated (isArrayElement .MaxOccurs)}}
+	{{if .Doc}} {{.Doc | comment}} {{end}}
+	{{if not .Type}}
+		//not type
+		{{if .SimpleType}}
+			//simple
+			{{if .SimpleType.Doc}} {{.SimpleType.Doc | comment}} {{end}}
+			{{ replaceReservedWords .Name | makePublic}} {{toGoType .SimpleType.Restriction.Base}} ` + "`" + `xml:"{{elementName .Name .Form}},omitempty"` + "`" + `
+		{{else if .Ref}}
+			//ref
+			{{$elementType := findType .Ref }}
+			{{if $array}}//MAX OCCUR {{ .MaxOccurs }}{{end}}
+			{{ if .Name }}
+				{{stripns .Ref | replaceReservedWords | makePublic}} {{if $array}}[]{{end}}{{ $elementType }} ` + "`" + `xml:"{{.Name}},omitempty"` + "`" + `
+			{{else if qualifiedName .Ref}}
+				{{stripns .Ref | replaceReservedWords | makePublic}} {{if $array}}[]{{end}}{{ $elementType }} ` + "`" + `xml:"{{qualifiedName .Ref}},omitempty"` + "`" + `
 			{{else}}
-				//else
-				{{$elementType := findType .Name }}
-				{{if isArrayElement .MaxOccurs }}//MAX OCCUR {{ .MaxOccurs }}{{end}}
-				{{replaceReservedWords .Name | makePublic}} {{if isArrayElement .MaxOccurs }}[]{{end}}{{ $elementType }} ` + "`" + `xml:"{{.Name}},omitempty"` + "`" + `
+				{{stripns .Ref | replaceReservedWords | makePublic}} {{if $array}}[]{{end}}{{ $elementType }}
 			{{end}}
 		{{else}}
-			//type
-			{{ $isBaseType := isBaseType .Type }}
-			{{if isArrayElement .MaxOccurs }}//MAX OCCUR {{ .MaxOccurs }}{{end}}
-			{{ if $isBaseType }}
-				//basetype
-				{{replaceReservedWords .Name | makePublic}} {{if isArrayElement .MaxOccurs }}[]{{end}}{{ toGoType .Type }} ` + "`" + `xml:"{{.Name}},omitempty"` + "`" + `
-			{{ else }}
-				//else
-				{{$elementType := findType .Type }}
-				{{replaceReservedWords .Name | makePublic}} {{if isArrayElement .MaxOccurs }}[]{{end}}{{ $elementType }} ` + "`" + `xml:"{{.Name}},omitempty"` + "`" + `
-			{{ end }}
+			//else
+			{{$elementType := title .Name | replaceReservedWords | makePublic | printf "*%s" }}
+			{{if $array}}//MAX OCCUR {{ .MaxOccurs }}{{end}}
+			{{replaceReservedWords .Name | makePublic}} {{if $array}}[]{{end}}{{ $elementType }} ` + "`" + `xml:"{{elementName .Name .Form}},omitempty"` + "`" + `
 		{{end}}
+	{{else}}
+		//type
+		{{ $isBaseType := isBaseType .Type }}
+		{{if $array}}//MAX OCCUR {{ .MaxOccurs }}{{end}}
+		{{ if $isBaseType }}
+			//basetype
+			{{replaceReservedWords .Name | makePublic}} {{if $array}}[]{{end}}{{ toGoType .Type }} ` + "`" + `xml:"{{elementName .Name .Form}},omitempty"` + "`" + `
+		{{ else }}
+			//else
+			{{$elementType := or (polymorphicType .Type) (findType .Type) }}
+			{{replaceReservedWords .Name | makePublic}} {{if $array}}[]{{end}}{{ $elementType }} ` + "`" + `xml:"{{elementName .Name .Form}},omitempty"` + "`" + `
+		{{ end }}
+	{{end}}
 	{{end}}
 {{end}}
 
@@ -296,7 +494,7 @@ var typesTmpl = `
 			{{template "ComplexTypeLocal" dictValues "ParentName" "" "Value" .}}
 		{{else}}
 			//ELEMENT TYPE
-			{{$name := title .Name | replaceReservedWords | makePublic}}
+			{{$name := elementTypeName .Name}}
 			{{if processComplexType $name}}
 				{{if .Doc}} {{.Doc | comment}} {{end}}
 				type {{ $name }} struct {
@@ -309,7 +507,14 @@ var typesTmpl = `
 					{{if isArrayElement .MaxOccurs }}//MAX OCCUR {{ .MaxOccurs }}{{end}}
 					{{ $isBaseType := isBaseType .Type }}
 					{{if not $isBaseType}}
-						{{replaceReservedWords .Type | makePublic}} {{if isArrayElement .MaxOccurs }}[]{{end}}{{ title .Type | replaceReservedWords | toGoType}}
+						{{$elementType := findType .Type }}
+						{{if eq $elementType "*interface{}"}}
+							//{{$elementType}}
+						{{else if isArrayElement .MaxOccurs }}
+							{{stripns .Type | replaceReservedWords | makePublic}} []{{$elementType}}
+						{{else}}
+							{{$elementType}}
+						{{end}}
 					{{else}}
 						{{replaceReservedWords .Name | makePublic}} {{if isArrayElement .MaxOccurs }}[]{{end}}{{ .Type | replaceReservedWords | toGoType}}
 					{{end}}
@@ -317,6 +522,15 @@ var typesTmpl = `
 			{{end}}
 		{{end}}
 	{{end}}
+	{{with xsiTypes}}
+		// Registers the types of the schema which may be named by xsi:type.
+		func init() {
+			gowsdl.RegisterTypes(gowsdl.TypeRegistry{
+				{{range .}}
+					{Space: "{{.Name.Space}}", Local: "{{.Name.Local}}"}: func() interface{} { return new({{.GoName}}) },
+				{{end}}
+			})
+		}
+	{{end}}
 
-	{{template "AttributeGroups" .AttributeGoups}}
 `
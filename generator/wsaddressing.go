@@ -0,0 +1,99 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"crypto/rand"
+	"encoding/xml"
+	"fmt"
+)
+
+const (
+	WsaNamespace = "http://www.w3.org/2005/08/addressing"
+	WsaAnonymous = "http://www.w3.org/2005/08/addressing/anonymous"
+
+	// Namespaces of the WS-Addressing WSDL binding and metadata, where the
+	// wsaw:UsingAddressing element and the wsaw:Action / wsam:Action
+	// attributes are defined.
+	WsawNamespace = "http://www.w3.org/2006/05/addressing/wsdl"
+	WsamNamespace = "http://www.w3.org/2007/05/addressing/metadata"
+)
+
+// WSAddressingProperty is a WS-Addressing header block with a single value,
+// ie. wsa:Action, wsa:To or wsa:MessageID.
+type WSAddressingProperty struct {
+	XMLName        xml.Name
+	MustUnderstand *xml.Attr `xml:",any,attr"`
+	Value          string    `xml:",chardata"`
+}
+
+// WSAddressingEndpoint is an endpoint reference header block, ie. wsa:ReplyTo.
+type WSAddressingEndpoint struct {
+	XMLName xml.Name
+	Address string `xml:"http://www.w3.org/2005/08/addressing Address"`
+}
+
+type wsaResponseEnvelope struct {
+	Header struct {
+		RelatesTo string `xml:"http://www.w3.org/2005/08/addressing RelatesTo"`
+	} `xml:"Header"`
+}
+
+// Returns the WS-Addressing header blocks of a request: the action, the
+// destination, a new message id and an anonymous reply endpoint.
+func addressingHeaders(action, to, messageID string, soap12 bool) []interface{} {
+	return []interface{}{
+		&WSAddressingProperty{
+			XMLName:        xml.Name{Space: WsaNamespace, Local: "Action"},
+			MustUnderstand: mustUnderstandAttr(soap12),
+			Value:          action,
+		},
+		&WSAddressingProperty{
+			XMLName:        xml.Name{Space: WsaNamespace, Local: "To"},
+			MustUnderstand: mustUnderstandAttr(soap12),
+			Value:          to,
+		},
+		&WSAddressingProperty{
+			XMLName: xml.Name{Space: WsaNamespace, Local: "MessageID"},
+			Value:   messageID,
+		},
+		&WSAddressingEndpoint{
+			XMLName: xml.Name{Space: WsaNamespace, Local: "ReplyTo"},
+			Address: WsaAnonymous,
+		},
+	}
+}
+
+// Returns the wsa:RelatesTo of a response envelope, if any.
+func addressingRelatesTo(data []byte) string {
+	envelope := &wsaResponseEnvelope{}
+	if err := xml.Unmarshal(data, envelope); err != nil {
+		return ""
+	}
+	return envelope.Header.RelatesTo
+}
+
+// Generates a random (version 4) UUID message id.
+func newMessageID() (string, error) {
+	uuid := make([]byte, 16)
+	if _, err := rand.Read(uuid); err != nil {
+		return "", err
+	}
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:]), nil
+}
+
+// Returns the soap:mustUnderstand="1" attribute of the given SOAP version.
+func mustUnderstandAttr(soap12 bool) *xml.Attr {
+	namespace := soapEnvelopeNamespace
+	if soap12 {
+		namespace = soap12EnvelopeNamespace
+	}
+	return &xml.Attr{
+		Name:  xml.Name{Space: namespace, Local: "mustUnderstand"},
+		Value: "1",
+	}
+}
@@ -0,0 +1,92 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type receivedAddressing struct {
+	Header struct {
+		Action struct {
+			MustUnderstand string `xml:"http://www.w3.org/2003/05/soap-envelope mustUnderstand,attr"`
+			Value          string `xml:",chardata"`
+		} `xml:"http://www.w3.org/2005/08/addressing Action"`
+		To        string `xml:"http://www.w3.org/2005/08/addressing To"`
+		MessageID string `xml:"http://www.w3.org/2005/08/addressing MessageID"`
+		ReplyTo   struct {
+			Address string `xml:"http://www.w3.org/2005/08/addressing Address"`
+		} `xml:"http://www.w3.org/2005/08/addressing ReplyTo"`
+	} `xml:"Header"`
+}
+
+func TestWithWSAddressing(t *testing.T) {
+	var received receivedAddressing
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		xml.Unmarshal(data, &received)
+		w.Write([]byte(`<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:wsa="http://www.w3.org/2005/08/addressing">
+	<env:Header><wsa:RelatesTo>` + received.Header.MessageID + `</wsa:RelatesTo></env:Header>
+	<env:Body><PingResponse xmlns="http://example.com/ping"><Message>pong</Message></PingResponse></env:Body>
+</env:Envelope>`))
+	}))
+	defer ts.Close()
+
+	client := NewSoap12Client(ts.URL, false, WithWSAddressing())
+
+	header := &SoapHeader{}
+	err := client.Call("http://example.com/ping/Ping", &pingRequest{}, &pingResponse{}, header, nil)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	if received.Header.Action.Value != "http://example.com/ping/Ping" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", received.Header.Action.Value, "http://example.com/ping/Ping")
+	}
+	if received.Header.Action.MustUnderstand != "1" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", received.Header.Action.MustUnderstand, "1")
+	}
+	if received.Header.To != ts.URL {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", received.Header.To, ts.URL)
+	}
+	if received.Header.ReplyTo.Address != WsaAnonymous {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", received.Header.ReplyTo.Address, WsaAnonymous)
+	}
+	if !strings.HasPrefix(header.MessageID, "urn:uuid:") || header.MessageID != received.Header.MessageID {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", header.MessageID, received.Header.MessageID)
+	}
+	if header.RelatesTo != header.MessageID {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", header.RelatesTo, header.MessageID)
+	}
+	if len(header.Headers) != 0 {
+		t.Errorf("the caller header was modified: %#v", header.Headers)
+	}
+}
+
+func TestWithoutWSAddressing(t *testing.T) {
+	var received receivedAddressing
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		xml.Unmarshal(data, &received)
+		w.Write([]byte(pongEnvelope))
+	}))
+	defer ts.Close()
+
+	// As passed to the constructor of a generated client using WS-Addressing.
+	client := NewSoapClient(ts.URL, false, WithWSAddressing(), WithoutWSAddressing())
+
+	header := &SoapHeader{}
+	err := client.Call("http://example.com/ping/Ping", &pingRequest{}, &pingResponse{}, header, nil)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	if received.Header.Action.Value != "" || received.Header.MessageID != "" || header.MessageID != "" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: no WS-Addressing headers", received.Header)
+	}
+}
@@ -3,7 +3,12 @@
 // file, You can obtain one at http://mozilla.org/MPL/2.0/.
 package generator
 
+import (
+	"encoding/xml"
+)
+
 type Wsdl struct {
+	Xmlns           map[string]string `xml:"-"`
 	Name            string          `xml:"name,attr"`
 	TargetNamespace string          `xml:"targetNamespace,attr"`
 	Imports         []*WsdlImport   `xml:"import"`
@@ -13,6 +18,22 @@ type Wsdl struct {
 	PortTypes       []*WsdlPortType `xml:"http://schemas.xmlsoap.org/wsdl/ portType"`
 	Binding         []*WsdlBinding  `xml:"http://schemas.xmlsoap.org/wsdl/ binding"`
 	Service         []*WsdlService  `xml:"http://schemas.xmlsoap.org/wsdl/ service"`
+	// WS-Policy 1.2 or 1.5 policies, referenced by the bindings.
+	Policies []*WsdlPolicy `xml:"Policy"`
+}
+
+func (w *Wsdl) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type wsdl Wsdl
+	if err := d.DecodeElement((*wsdl)(w), &start); err != nil {
+		return err
+	}
+	w.Xmlns = namespaceDecls(start.Attr)
+	return nil
+}
+
+// Resolves a QName of the definitions, ie. the element or type of a part.
+func (w *Wsdl) resolveQName(qname string) xml.Name {
+	return resolveQName(w.Xmlns, qname)
 }
 
 type WsdlImport struct {
@@ -38,35 +59,45 @@ type WsdlMessage struct {
 }
 
 type WsdlFault struct {
-	Name      string        `xml:"name,attr"`
-	Message   string        `xml:"message,attr"`
-	Doc       string        `xml:"documentation"`
-	SoapFault WsdlSoapFault `xml:"http://schemas.xmlsoap.org/wsdl/soap/ fault"`
+	Name        string        `xml:"name,attr"`
+	Message     string        `xml:"message,attr"`
+	Doc         string        `xml:"documentation"`
+	SoapFault   WsdlSoapFault `xml:"http://schemas.xmlsoap.org/wsdl/soap/ fault"`
+	Soap12Fault WsdlSoapFault `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ fault"`
 }
 
 type WsdlInput struct {
-	Name       string            `xml:"name,attr"`
-	Message    string            `xml:"message,attr"`
-	Doc        string            `xml:"documentation"`
-	SoapBody   WsdlSoapBody      `xml:"http://schemas.xmlsoap.org/wsdl/soap/ body"`
-	SoapHeader []*WsdlSoapHeader `xml:"http://schemas.xmlsoap.org/wsdl/soap/ header"`
+	Name         string            `xml:"name,attr"`
+	Message      string            `xml:"message,attr"`
+	Doc          string            `xml:"documentation"`
+	WsawAction   string            `xml:"http://www.w3.org/2006/05/addressing/wsdl Action,attr"`
+	WsamAction   string            `xml:"http://www.w3.org/2007/05/addressing/metadata Action,attr"`
+	SoapBody     WsdlSoapBody      `xml:"http://schemas.xmlsoap.org/wsdl/soap/ body"`
+	SoapHeader   []*WsdlSoapHeader `xml:"http://schemas.xmlsoap.org/wsdl/soap/ header"`
+	Soap12Body   WsdlSoapBody      `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ body"`
+	Soap12Header []*WsdlSoapHeader `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ header"`
 }
 
 type WsdlOutput struct {
-	Name       string            `xml:"name,attr"`
-	Message    string            `xml:"message,attr"`
-	Doc        string            `xml:"documentation"`
-	SoapBody   WsdlSoapBody      `xml:"http://schemas.xmlsoap.org/wsdl/soap/ body"`
-	SoapHeader []*WsdlSoapHeader `xml:"http://schemas.xmlsoap.org/wsdl/soap/ header"`
+	Name         string            `xml:"name,attr"`
+	Message      string            `xml:"message,attr"`
+	Doc          string            `xml:"documentation"`
+	WsawAction   string            `xml:"http://www.w3.org/2006/05/addressing/wsdl Action,attr"`
+	WsamAction   string            `xml:"http://www.w3.org/2007/05/addressing/metadata Action,attr"`
+	SoapBody     WsdlSoapBody      `xml:"http://schemas.xmlsoap.org/wsdl/soap/ body"`
+	SoapHeader   []*WsdlSoapHeader `xml:"http://schemas.xmlsoap.org/wsdl/soap/ header"`
+	Soap12Body   WsdlSoapBody      `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ body"`
+	Soap12Header []*WsdlSoapHeader `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ header"`
 }
 
 type WsdlOperation struct {
-	Name          string            `xml:"name,attr"`
-	Doc           string            `xml:"documentation"`
-	Input         WsdlInput         `xml:"input"`
-	Output        WsdlOutput        `xml:"output"`
-	Faults        []*WsdlFault      `xml:"fault"`
-	SoapOperation WsdlSoapOperation `xml:"http://schemas.xmlsoap.org/wsdl/soap/ operation"`
+	Name            string            `xml:"name,attr"`
+	Doc             string            `xml:"documentation"`
+	Input           WsdlInput         `xml:"input"`
+	Output          WsdlOutput        `xml:"output"`
+	Faults          []*WsdlFault      `xml:"fault"`
+	SoapOperation   WsdlSoapOperation `xml:"http://schemas.xmlsoap.org/wsdl/soap/ operation"`
+	Soap12Operation WsdlSoapOperation `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ operation"`
 }
 
 type WsdlPortType struct {
@@ -121,18 +152,80 @@ type WsdlSoapAddress struct {
 }
 
 type WsdlBinding struct {
-	Name        string           `xml:"name,attr"`
-	Type        string           `xml:"type,attr"`
-	Doc         string           `xml:"documentation"`
-	SoapBinding WsdlSoapBinding  `xml:"http://schemas.xmlsoap.org/wsdl/soap/ binding"`
-	Operations  []*WsdlOperation `xml:"http://schemas.xmlsoap.org/wsdl/ operation"`
+	Name          string           `xml:"name,attr"`
+	Type          string           `xml:"type,attr"`
+	Doc           string           `xml:"documentation"`
+	SoapBinding   WsdlSoapBinding  `xml:"http://schemas.xmlsoap.org/wsdl/soap/ binding"`
+	Soap12Binding WsdlSoapBinding  `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ binding"`
+	Operations    []*WsdlOperation `xml:"http://schemas.xmlsoap.org/wsdl/ operation"`
+	// wsaw:UsingAddressing, set when the binding uses WS-Addressing
+	UsingAddressing *WsdlUsingAddressing `xml:"http://www.w3.org/2006/05/addressing/wsdl UsingAddressing"`
+	// Policies of the binding, either inline or referenced.
+	Policies         []*WsdlPolicy          `xml:"Policy"`
+	PolicyReferences []*WsdlPolicyReference `xml:"PolicyReference"`
+}
+
+type WsdlUsingAddressing struct {
+	Required string `xml:"http://schemas.xmlsoap.org/wsdl/ required,attr"`
+}
+
+// WsdlPolicy is a WS-Policy of which only the WS-Addressing assertions,
+// wsam:Addressing or wsaw:UsingAddressing whatever their nesting in
+// wsp:ExactlyOne and wsp:All, and the nested policy references are kept.
+type WsdlPolicy struct {
+	Id               string
+	Addressing       bool
+	PolicyReferences []*WsdlPolicyReference
+}
+
+func (p *WsdlPolicy) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		// wsu:Id, xml:id or the Name of WS-Policy 1.5
+		if attr.Name.Local == "Id" || attr.Name.Local == "id" || attr.Name.Local == "Name" {
+			p.Id = attr.Value
+		}
+	}
+
+	for depth := 0; ; {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch t.Name {
+			case xml.Name{Space: WsamNamespace, Local: "Addressing"}, xml.Name{Space: WsawNamespace, Local: "UsingAddressing"}:
+				p.Addressing = true
+			}
+			if t.Name.Local == "PolicyReference" {
+				reference := &WsdlPolicyReference{}
+				if err := d.DecodeElement(reference, &t); err != nil {
+					return err
+				}
+				p.PolicyReferences = append(p.PolicyReferences, reference)
+				depth--
+			}
+		case xml.EndElement:
+			if depth == 0 {
+				return nil
+			}
+			depth--
+		}
+	}
+}
+
+type WsdlPolicyReference struct {
+	URI string `xml:"URI,attr"`
 }
 
 type WsdlPort struct {
-	Name        string          `xml:"name,attr"`
-	Binding     string          `xml:"binding,attr"`
-	Doc         string          `xml:"documentation"`
-	SoapAddress WsdlSoapAddress `xml:"http://schemas.xmlsoap.org/wsdl/soap/ address"`
+	Name          string          `xml:"name,attr"`
+	Binding       string          `xml:"binding,attr"`
+	Doc           string          `xml:"documentation"`
+	SoapAddress   WsdlSoapAddress `xml:"http://schemas.xmlsoap.org/wsdl/soap/ address"`
+	Soap12Address WsdlSoapAddress `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ address"`
 }
 
 type WsdlService struct {
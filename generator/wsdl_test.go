@@ -31,3 +31,51 @@ func TestUnmarshal(t *testing.T) {
 
 	// t.Logf("%#v\n", v.Types.Schema[0].Includes)
 }
+
+func TestUnmarshalSoap12(t *testing.T) {
+	data, err := ioutil.ReadFile("fixtures/ferry.wsdl")
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	v := Wsdl{}
+	err = xml.Unmarshal(data, &v)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	binding := v.Binding[1]
+	if binding.Soap12Binding.Transport != "http://schemas.xmlsoap.org/soap/http" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", binding.Soap12Binding.Transport, "http://schemas.xmlsoap.org/soap/http")
+	}
+
+	action := binding.Operations[0].Soap12Operation.SoapAction
+	if action != "http://www.wsdot.wa.gov/ferries/schedule/GetActiveScheduledSeasons" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", action, "http://www.wsdot.wa.gov/ferries/schedule/GetActiveScheduledSeasons")
+	}
+
+	location := v.Service[0].Ports[1].Soap12Address.Location
+	if location != "http://b2b.wsdot.wa.gov/ferries/schedule/Default.asmx" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", location, "http://b2b.wsdot.wa.gov/ferries/schedule/Default.asmx")
+	}
+}
+
+func TestResolveQName(t *testing.T) {
+	xmlns := map[string]string{
+		"":    "http://www.w3.org/2001/XMLSchema",
+		"tns": "http://example.com/orders",
+	}
+	tests := map[string]xml.Name{
+		"tns:Order":  {Space: "http://example.com/orders", Local: "Order"},
+		"string":     {Space: "http://www.w3.org/2001/XMLSchema", Local: "string"},
+		"xml:lang":   {Space: "http://www.w3.org/XML/1998/namespace", Local: "lang"},
+		"other:Item": {Space: "", Local: "Item"},
+	}
+
+	for qname, want := range tests {
+		got := resolveQName(xmlns, qname)
+		if got != want {
+			t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", got, want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"time"
+)
+
+const (
+	WsseNamespace = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"
+	WsuNamespace  = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+
+	WssPasswordText   = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordText"
+	WssPasswordDigest = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest"
+	WssBase64Binary   = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary"
+)
+
+// Layout of wsu:Created and wsu:Expires, always in UTC.
+const wsuTimeLayout = "2006-01-02T15:04:05.000Z"
+
+// WSSecurity describes the wsse:Security header added to every call of a
+// SoapClient configured with WithWSSecurity. A new header, with a fresh nonce
+// and timestamp, is built for each call.
+type WSSecurity struct {
+	// UsernameToken credentials, no token is sent when Username is empty.
+	Username string
+	Password string
+	// Sends Base64(SHA-1(nonce + created + password)) instead of the
+	// password in clear text.
+	PasswordDigest bool
+
+	// Adds a wsu:Timestamp expiring TimestampTTL after its creation, no
+	// timestamp is sent when it is zero.
+	TimestampTTL time.Duration
+
+	// Flags the header with soap:mustUnderstand="1".
+	MustUnderstand bool
+}
+
+type WSSecurityHeader struct {
+	XMLName        xml.Name  `xml:"wsse:Security"`
+	XmlnsWsse      string    `xml:"xmlns:wsse,attr"`
+	XmlnsWsu       string    `xml:"xmlns:wsu,attr"`
+	MustUnderstand *xml.Attr `xml:",any,attr"`
+
+	Timestamp     *WSSecurityTimestamp     `xml:",omitempty"`
+	UsernameToken *WSSecurityUsernameToken `xml:",omitempty"`
+}
+
+type WSSecurityTimestamp struct {
+	XMLName xml.Name `xml:"wsu:Timestamp"`
+	Id      string   `xml:"wsu:Id,attr,omitempty"`
+	Created string   `xml:"wsu:Created"`
+	Expires string   `xml:"wsu:Expires,omitempty"`
+}
+
+type WSSecurityUsernameToken struct {
+	XMLName  xml.Name           `xml:"wsse:UsernameToken"`
+	Id       string             `xml:"wsu:Id,attr,omitempty"`
+	Username string             `xml:"wsse:Username"`
+	Password WSSecurityPassword `xml:"wsse:Password"`
+	Nonce    *WSSecurityNonce   `xml:"wsse:Nonce,omitempty"`
+	Created  string             `xml:"wsu:Created,omitempty"`
+}
+
+type WSSecurityPassword struct {
+	Type  string `xml:"Type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type WSSecurityNonce struct {
+	EncodingType string `xml:"EncodingType,attr"`
+	Value        string `xml:",chardata"`
+}
+
+// Header builds a new wsse:Security header for a call, soap12 selects the
+// namespace of the mustUnderstand attribute.
+func (w *WSSecurity) Header(soap12 bool) (*WSSecurityHeader, error) {
+	header := &WSSecurityHeader{
+		XmlnsWsse: WsseNamespace,
+		XmlnsWsu:  WsuNamespace,
+	}
+
+	if w.MustUnderstand {
+		header.MustUnderstand = mustUnderstandAttr(soap12)
+	}
+
+	now := time.Now().UTC()
+	created := now.Format(wsuTimeLayout)
+
+	if w.TimestampTTL > 0 {
+		id, err := newWsuId("TS")
+		if err != nil {
+			return nil, err
+		}
+		header.Timestamp = &WSSecurityTimestamp{
+			Id:      id,
+			Created: created,
+			Expires: now.Add(w.TimestampTTL).Format(wsuTimeLayout),
+		}
+	}
+
+	if w.Username != "" {
+		id, err := newWsuId("UsernameToken")
+		if err != nil {
+			return nil, err
+		}
+		token := &WSSecurityUsernameToken{
+			Id:       id,
+			Username: w.Username,
+			Password: WSSecurityPassword{Type: WssPasswordText, Value: w.Password},
+		}
+
+		if w.PasswordDigest {
+			nonce := make([]byte, 16)
+			if _, err := rand.Read(nonce); err != nil {
+				return nil, err
+			}
+			token.Nonce = &WSSecurityNonce{
+				EncodingType: WssBase64Binary,
+				Value:        base64.StdEncoding.EncodeToString(nonce),
+			}
+			token.Created = created
+			token.Password = WSSecurityPassword{
+				Type:  WssPasswordDigest,
+				Value: passwordDigest(nonce, created, w.Password),
+			}
+		}
+		header.UsernameToken = token
+	}
+
+	return header, nil
+}
+
+// Computes the UsernameToken password digest,
+// Base64(SHA-1(nonce + created + password)).
+func passwordDigest(nonce []byte, created, password string) string {
+	h := sha1.New()
+	h.Write(nonce)
+	h.Write([]byte(created))
+	h.Write([]byte(password))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Generates a random wsu:Id with the given prefix.
+func newWsuId(prefix string) (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return prefix + "-" + hex.EncodeToString(id), nil
+}
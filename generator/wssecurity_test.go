@@ -0,0 +1,112 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type receivedSecurity struct {
+	Header struct {
+		Security struct {
+			MustUnderstand string `xml:"http://schemas.xmlsoap.org/soap/envelope/ mustUnderstand,attr"`
+			Timestamp      struct {
+				Created string `xml:"Created"`
+				Expires string `xml:"Expires"`
+			} `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Timestamp"`
+			UsernameToken struct {
+				Username string `xml:"Username"`
+				Password struct {
+					Type  string `xml:"Type,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"Password"`
+				Nonce   string `xml:"Nonce"`
+				Created string `xml:"Created"`
+			} `xml:"UsernameToken"`
+		} `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Security"`
+	} `xml:"Header"`
+}
+
+func TestPasswordDigest(t *testing.T) {
+	// Example from the WS-Security UsernameToken profile
+	nonce, _ := base64.StdEncoding.DecodeString("LKqI6G/AikKCQrN0zqZFlg==")
+	got := passwordDigest(nonce, "2010-09-16T07:50:45Z", "userpassword")
+	if got != "tuOSpGlFlIXsozq4HFNeeGeFLEI=" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", got, "tuOSpGlFlIXsozq4HFNeeGeFLEI=")
+	}
+}
+
+func TestWithWSSecurity(t *testing.T) {
+	var received receivedSecurity
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		xml.Unmarshal(data, &received)
+		w.Write([]byte(pongEnvelope))
+	}))
+	defer ts.Close()
+
+	client := NewSoapClient(ts.URL, false, WithWSSecurity(&WSSecurity{
+		Username:       "john",
+		Password:       "secret",
+		PasswordDigest: true,
+		TimestampTTL:   5 * time.Minute,
+		MustUnderstand: true,
+	}))
+
+	header := &SoapHeader{}
+	err := client.Call("", &pingRequest{}, &pingResponse{}, header, nil)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	if len(header.Headers) != 0 {
+		t.Errorf("the caller header was modified: %#v", header.Headers)
+	}
+
+	security := received.Header.Security
+	if security.MustUnderstand != "1" {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", security.MustUnderstand, "1")
+	}
+
+	created, err := time.Parse(wsuTimeLayout, security.Timestamp.Created)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	expires, _ := time.Parse(wsuTimeLayout, security.Timestamp.Expires)
+	if expires.Sub(created) != 5*time.Minute {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", expires.Sub(created), 5*time.Minute)
+	}
+
+	token := security.UsernameToken
+	if token.Username != "john" || token.Password.Type != WssPasswordDigest {
+		t.Errorf("incorrect result\ngot:  %#v", token)
+	}
+	nonce, _ := base64.StdEncoding.DecodeString(token.Nonce)
+	if want := passwordDigest(nonce, token.Created, "secret"); token.Password.Value != want {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", token.Password.Value, want)
+	}
+}
+
+func TestWSSecurityPasswordText(t *testing.T) {
+	security := &WSSecurity{Username: "john", Password: "secret"}
+	header, err := security.Header(false)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+
+	data, _ := xml.Marshal(header)
+	want := `<wsse:Password Type="` + WssPasswordText + `">secret</wsse:Password>`
+	if !strings.Contains(string(data), want) {
+		t.Errorf("incorrect result\ngot:  %s\nwant: %s", data, want)
+	}
+	if header.Timestamp != nil || header.UsernameToken.Nonce != nil {
+		t.Errorf("unexpected timestamp or nonce: %s", data)
+	}
+}
@@ -0,0 +1,211 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const unsignedPongEnvelope = `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+	<soap:Header><wsse:Security xmlns:wsse="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"></wsse:Security></soap:Header>
+	<soap:Body><PingResponse xmlns="http://example.com/ping"><Message>pong</Message></PingResponse></soap:Body>
+</soap:Envelope>`
+
+// Returns a pong envelope with a wsu:Timestamp expiring at expires.
+func timestampedPongEnvelope(expires time.Time) string {
+	return strings.Replace(unsignedPongEnvelope, "></wsse:Security>",
+		` xmlns:wsu="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd">`+
+			`<wsu:Timestamp wsu:Id="TS-1"><wsu:Created>`+expires.Add(-time.Minute).UTC().Format(wsuTimeLayout)+`</wsu:Created>`+
+			`<wsu:Expires>`+expires.UTC().Format(wsuTimeLayout)+`</wsu:Expires></wsu:Timestamp></wsse:Security>`, 1)
+}
+
+// Generates a self-signed certificate for key.
+func newTestCertificate(t *testing.T, key crypto.Signer) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "gowsdl test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificate
+}
+
+func newTestSignature(t *testing.T, ecdsaKey bool) *WSSignature {
+	var key crypto.Signer
+	var err error
+	if ecdsaKey {
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	} else {
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &WSSignature{Certificate: newTestCertificate(t, key), PrivateKey: key}
+}
+
+func TestExcC14N(t *testing.T) {
+	tests := []struct {
+		input    string
+		path     []string
+		expected string
+	}{
+		// Example of the Exclusive XML Canonicalization specification
+		{
+			`<n0:local xmlns:n0="foo:bar" xmlns:n3="ftp://example.org"><n1:elem2 xmlns:n1="http://example.net" xml:lang="en"><n3:stuff xmlns:n3="ftp://example.org"/></n1:elem2></n0:local>`,
+			[]string{"elem2"},
+			`<n1:elem2 xmlns:n1="http://example.net" xml:lang="en"><n3:stuff xmlns:n3="ftp://example.org"></n3:stuff></n1:elem2>`,
+		},
+		{
+			`<a xmlns="urn:x" xmlns:y="urn:y" y:c="3" b="2" a="1&amp;&#9;"><b xmlns="">x &gt; y</b></a>`,
+			nil,
+			`<a xmlns="urn:x" xmlns:y="urn:y" a="1&amp;&#x9;" b="2" y:c="3"><b xmlns="">x &gt; y</b></a>`,
+		},
+	}
+
+	for _, test := range tests {
+		el, err := parseXMLElement([]byte(test.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, local := range test.path {
+			for _, child := range el.Children {
+				if c, ok := child.(*xmlElement); ok && c.Local == local {
+					el = c
+				}
+			}
+		}
+
+		got := string(el.canonicalize(nil))
+		if got != test.expected {
+			t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", got, test.expected)
+		}
+	}
+}
+
+func TestWithWSSignature(t *testing.T) {
+	for _, ecdsaKey := range []bool{false, true} {
+		client := newTestSignature(t, ecdsaKey)
+		server := newTestSignature(t, ecdsaKey)
+
+		var requestErr error
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			data, _ := ioutil.ReadAll(r.Body)
+			verifier := &WSSignature{TrustedCertificates: []*x509.Certificate{client.Certificate}}
+			requestErr = verifier.verify(data)
+
+			response, err := server.sign([]byte(unsignedPongEnvelope))
+			if err != nil {
+				t.Error(err)
+			}
+			w.Write(response)
+		}))
+
+		client.VerifyResponses = true
+		client.TrustedCertificates = []*x509.Certificate{server.Certificate}
+		soapClient := NewSoapClient(ts.URL, false,
+			WithWSSecurity(&WSSecurity{TimestampTTL: time.Minute}),
+			WithWSSignature(client))
+
+		response := &pingResponse{}
+		err := soapClient.Call("", &pingRequest{Message: "ping"}, response, nil, nil)
+		if err != nil {
+			t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+		}
+		if requestErr != nil {
+			t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", requestErr, nil)
+		}
+		if response.Message != "pong" {
+			t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", response.Message, "pong")
+		}
+
+		ts.Close()
+	}
+}
+
+func TestWSSignatureTimestamp(t *testing.T) {
+	server := newTestSignature(t, false)
+	signed, err := server.sign([]byte(timestampedPongEnvelope(time.Now().Add(time.Minute))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := &WSSignature{TrustedCertificates: []*x509.Certificate{server.Certificate}}
+	if err := verifier.verify(signed); err != nil {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+}
+
+func TestWSSignatureRejectsResponses(t *testing.T) {
+	server := newTestSignature(t, false)
+	signed, err := server.sign([]byte(unsignedPongEnvelope))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A second body after the signed one, which encoding/xml would decode.
+	wrapped := strings.Replace(string(signed), "</soap:Envelope>",
+		`<soap:Body><PingResponse xmlns="http://example.com/ping"><Message>evil</Message></PingResponse></soap:Body></soap:Envelope>`, 1)
+
+	expired, err := server.sign([]byte(timestampedPongEnvelope(time.Now().Add(-time.Minute))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsignedTimestamp := strings.Replace(string(signed), "<wsse:Security ",
+		`<wsse:Security xmlns:wsu="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd" `, 1)
+	unsignedTimestamp = strings.Replace(unsignedTimestamp, "</wsse:Security>",
+		`<wsu:Timestamp><wsu:Expires>2999-01-01T00:00:00.000Z</wsu:Expires></wsu:Timestamp></wsse:Security>`, 1)
+
+	tests := []struct {
+		name     string
+		response string
+		trusted  *x509.Certificate
+	}{
+		{"unsigned", pongEnvelope, server.Certificate},
+		{"tampered", strings.Replace(string(signed), "pong", "p0ng", 1), server.Certificate},
+		{"untrusted", string(signed), newTestSignature(t, false).Certificate},
+		{"wrapped", wrapped, server.Certificate},
+		{"expired", string(expired), server.Certificate},
+		{"unsigned timestamp", unsignedTimestamp, server.Certificate},
+	}
+
+	for _, test := range tests {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(test.response))
+		}))
+
+		signature := newTestSignature(t, false)
+		signature.VerifyResponses = true
+		signature.TrustedCertificates = []*x509.Certificate{test.trusted}
+		client := NewSoapClient(ts.URL, false, WithWSSignature(signature))
+
+		err := client.Call("", &pingRequest{}, &pingResponse{}, nil, nil)
+		if err == nil {
+			t.Errorf("%s: the response was not rejected", test.name)
+		}
+
+		ts.Close()
+	}
+}
@@ -0,0 +1,329 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Minimal DOM used by the WS-Security signatures. Unlike encoding/xml it
+// keeps namespace prefixes and declarations as they are written, which is
+// required to canonicalize elements and to write a document back without
+// altering the parts that were signed.
+
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+type xmlElement struct {
+	Prefix   string
+	Local    string
+	Attrs    []xml.Attr    // as written, Name.Space holds the prefix
+	Children []interface{} // *xmlElement or xmlText
+	Parent   *xmlElement
+}
+
+type xmlText string
+
+// Parses a document, or a fragment with a single root element, into a DOM.
+// Comments, processing instructions and directives are dropped.
+func parseXMLElement(data []byte) (*xmlElement, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var root, current *xmlElement
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			el := &xmlElement{
+				Prefix: t.Name.Space,
+				Local:  t.Name.Local,
+				Attrs:  append([]xml.Attr{}, t.Attr...),
+				Parent: current,
+			}
+			if current == nil {
+				if root != nil {
+					return nil, errors.New("xml: more than one root element")
+				}
+				root = el
+			} else {
+				current.Children = append(current.Children, el)
+			}
+			current = el
+		case xml.EndElement:
+			if current == nil || current.Prefix != t.Name.Space || current.Local != t.Name.Local {
+				return nil, errors.New("xml: unexpected end element </" + t.Name.Local + ">")
+			}
+			current = current.Parent
+		case xml.CharData:
+			if current != nil {
+				current.appendText(string(t))
+			}
+		}
+	}
+
+	if root == nil || current != nil {
+		return nil, errors.New("xml: unexpected end of document")
+	}
+	return root, nil
+}
+
+func (e *xmlElement) appendText(text string) {
+	if n := len(e.Children); n > 0 {
+		if last, ok := e.Children[n-1].(xmlText); ok {
+			e.Children[n-1] = last + xmlText(text)
+			return
+		}
+	}
+	e.Children = append(e.Children, xmlText(text))
+}
+
+func (e *xmlElement) appendChild(child *xmlElement) {
+	child.Parent = e
+	e.Children = append(e.Children, child)
+}
+
+// Resolves a prefix, "" being the default namespace, in the scope of e.
+func (e *xmlElement) lookupNamespace(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return xmlNamespace, true
+	}
+
+	for el := e; el != nil; el = el.Parent {
+		for _, attr := range el.Attrs {
+			if prefix == "" && attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+				return attr.Value, true
+			}
+			if prefix != "" && attr.Name.Space == "xmlns" && attr.Name.Local == prefix {
+				return attr.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (e *xmlElement) namespace() string {
+	namespace, _ := e.lookupNamespace(e.Prefix)
+	return namespace
+}
+
+func (e *xmlElement) is(namespace, local string) bool {
+	return e.Local == local && e.namespace() == namespace
+}
+
+func isNamespaceDecl(attr xml.Attr) bool {
+	return attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns")
+}
+
+// Returns the value of the attribute local in namespace, namespace being ""
+// for unqualified attributes.
+func (e *xmlElement) attr(namespace, local string) (string, bool) {
+	for _, attr := range e.Attrs {
+		if attr.Name.Local != local || isNamespaceDecl(attr) {
+			continue
+		}
+		if attr.Name.Space == "" && namespace == "" {
+			return attr.Value, true
+		}
+		if attr.Name.Space != "" {
+			if ns, _ := e.lookupNamespace(attr.Name.Space); ns == namespace {
+				return attr.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (e *xmlElement) setAttr(name xml.Name, value string) {
+	for i, attr := range e.Attrs {
+		if attr.Name == name {
+			e.Attrs[i].Value = value
+			return
+		}
+	}
+	e.Attrs = append(e.Attrs, xml.Attr{Name: name, Value: value})
+}
+
+// Returns the first child element named local in namespace.
+func (e *xmlElement) child(namespace, local string) *xmlElement {
+	for _, child := range e.Children {
+		if el, ok := child.(*xmlElement); ok && el.is(namespace, local) {
+			return el
+		}
+	}
+	return nil
+}
+
+// Returns the text content of the element.
+func (e *xmlElement) text() string {
+	var text string
+	for _, child := range e.Children {
+		switch c := child.(type) {
+		case xmlText:
+			text += string(c)
+		case *xmlElement:
+			text += c.text()
+		}
+	}
+	return text
+}
+
+// Calls fn for e and all its descendant elements, in document order.
+func (e *xmlElement) walk(fn func(*xmlElement)) {
+	fn(e)
+	for _, child := range e.Children {
+		if el, ok := child.(*xmlElement); ok {
+			el.walk(fn)
+		}
+	}
+}
+
+func (e *xmlElement) qname() string {
+	if e.Prefix == "" {
+		return e.Local
+	}
+	return e.Prefix + ":" + e.Local
+}
+
+func attrQName(attr xml.Attr) string {
+	if attr.Name.Space == "" {
+		return attr.Name.Local
+	}
+	return attr.Name.Space + ":" + attr.Name.Local
+}
+
+// Writes the element as it was parsed, keeping its namespace declarations.
+func (e *xmlElement) writeTo(buffer *bytes.Buffer) {
+	buffer.WriteString("<" + e.qname())
+	for _, attr := range e.Attrs {
+		buffer.WriteString(" " + attrQName(attr) + `="` + escapeC14NAttr(attr.Value) + `"`)
+	}
+	buffer.WriteString(">")
+	e.writeChildren(buffer, func(child *xmlElement) { child.writeTo(buffer) })
+	buffer.WriteString("</" + e.qname() + ">")
+}
+
+func (e *xmlElement) writeChildren(buffer *bytes.Buffer, writeElement func(*xmlElement)) {
+	for _, child := range e.Children {
+		switch c := child.(type) {
+		case xmlText:
+			buffer.WriteString(escapeC14NText(string(c)))
+		case *xmlElement:
+			writeElement(c)
+		}
+	}
+}
+
+// Exclusive XML Canonicalization 1.0, without comments, of the element and
+// its descendants. inclusivePrefixes is the InclusiveNamespaces PrefixList,
+// "#default" standing for the default namespace.
+func (e *xmlElement) canonicalize(inclusivePrefixes []string) []byte {
+	inclusive := make(map[string]bool, len(inclusivePrefixes))
+	for _, prefix := range inclusivePrefixes {
+		if prefix == "#default" {
+			prefix = ""
+		}
+		inclusive[prefix] = true
+	}
+
+	buffer := &bytes.Buffer{}
+	e.writeCanonical(buffer, map[string]string{}, inclusive)
+	return buffer.Bytes()
+}
+
+func (e *xmlElement) writeCanonical(buffer *bytes.Buffer, rendered map[string]string, inclusive map[string]bool) {
+	// Namespaces visibly utilized by the element and its attributes, plus the
+	// in scope ones listed in the PrefixList.
+	utilized := map[string]bool{e.Prefix: true}
+	for _, attr := range e.Attrs {
+		if !isNamespaceDecl(attr) && attr.Name.Space != "" && attr.Name.Space != "xml" {
+			utilized[attr.Name.Space] = true
+		}
+	}
+	for prefix := range inclusive {
+		if _, ok := e.lookupNamespace(prefix); ok {
+			utilized[prefix] = true
+		}
+	}
+
+	var prefixes []string
+	for prefix := range utilized {
+		namespace, _ := e.lookupNamespace(prefix)
+		current, ok := rendered[prefix]
+		if ok && current == namespace || !ok && prefix == "" && namespace == "" {
+			continue
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	scope := rendered
+	if len(prefixes) > 0 {
+		scope = make(map[string]string, len(rendered)+len(prefixes))
+		for prefix, namespace := range rendered {
+			scope[prefix] = namespace
+		}
+		for _, prefix := range prefixes {
+			scope[prefix], _ = e.lookupNamespace(prefix)
+		}
+	}
+
+	var attrs []xml.Attr
+	for _, attr := range e.Attrs {
+		if !isNamespaceDecl(attr) {
+			attrs = append(attrs, attr)
+		}
+	}
+	sort.SliceStable(attrs, func(i, j int) bool {
+		nsI, _ := e.lookupNamespace(attrs[i].Name.Space)
+		nsJ, _ := e.lookupNamespace(attrs[j].Name.Space)
+		if attrs[i].Name.Space == "" {
+			nsI = ""
+		}
+		if attrs[j].Name.Space == "" {
+			nsJ = ""
+		}
+		if nsI != nsJ {
+			return nsI < nsJ
+		}
+		return attrs[i].Name.Local < attrs[j].Name.Local
+	})
+
+	buffer.WriteString("<" + e.qname())
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			buffer.WriteString(` xmlns="` + escapeC14NAttr(scope[prefix]) + `"`)
+		} else {
+			buffer.WriteString(" xmlns:" + prefix + `="` + escapeC14NAttr(scope[prefix]) + `"`)
+		}
+	}
+	for _, attr := range attrs {
+		buffer.WriteString(" " + attrQName(attr) + `="` + escapeC14NAttr(attr.Value) + `"`)
+	}
+	buffer.WriteString(">")
+	e.writeChildren(buffer, func(child *xmlElement) { child.writeCanonical(buffer, scope, inclusive) })
+	buffer.WriteString("</" + e.qname() + ">")
+}
+
+var c14nTextReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+
+var c14nAttrReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+
+func escapeC14NText(text string) string {
+	return c14nTextReplacer.Replace(text)
+}
+
+func escapeC14NAttr(value string) string {
+	return c14nAttrReplacer.Replace(value)
+}
@@ -5,22 +5,106 @@ package generator
 
 import (
 	"encoding/xml"
+	"strings"
 )
 
 type XsdSchema struct {
 	Parent			   string
+	// Namespace declarations of the schema element, keyed by prefix, ""
+	// being the default namespace. Schemas of a WSDL inherit the ones of
+	// the definitions.
+	Xmlns              map[string]string `xml:"-"`
 	XMLName            xml.Name          `xml:"schema"`
 	Tns                string            `xml:"xmlns tns,attr"`
 	Xs                 string            `xml:"xmlns xs,attr"`
 	Version            string            `xml:"version,attr"`
 	TargetNamespace    string            `xml:"targetNamespace,attr"`
 	ElementFormDefault string            `xml:"elementFormDefault,attr"`
+	AttributeFormDefault string          `xml:"attributeFormDefault,attr"`
 	Includes           []*XsdInclude     `xml:"include"`
 	Imports            []*XsdImport      `xml:"import"`
 	Elements           []*XsdElement     `xml:"element"`
 	ComplexTypes       []*XsdComplexType `xml:"complexType"` //global
 	SimpleType         []*XsdSimpleType  `xml:"simpleType"`
 	AttributeGoups	   []*XsdAttributeGroup	 `xml:"attributeGroup"`
+	Groups             []*XsdGroup       `xml:"group"`
+}
+
+func (s *XsdSchema) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type schema XsdSchema
+	if err := d.DecodeElement((*schema)(s), &start); err != nil {
+		return err
+	}
+	s.Xmlns = namespaceDecls(start.Attr)
+	return nil
+}
+
+// Adds the namespace declarations of an enclosing element which the schema
+// doesn't override.
+func (s *XsdSchema) inheritNamespaces(xmlns map[string]string) {
+	if s.Xmlns == nil {
+		s.Xmlns = make(map[string]string, len(xmlns))
+	}
+	for prefix, namespace := range xmlns {
+		if _, ok := s.Xmlns[prefix]; !ok {
+			s.Xmlns[prefix] = namespace
+		}
+	}
+}
+
+// Resolves a QName of the schema, ie. the value of a type, ref or base
+// attribute, to its namespace and local name.
+func (s *XsdSchema) resolveQName(qname string) xml.Name {
+	return resolveQName(s.Xmlns, qname)
+}
+
+// Returns the name of a local element in struct tags, qualified with the
+// target namespace when its form, or else elementFormDefault, is qualified.
+func (s *XsdSchema) elementName(name, form string) string {
+	return s.localName(name, form, s.ElementFormDefault)
+}
+
+// Returns the name of a local attribute in struct tags, qualified with the
+// target namespace when its form, or else attributeFormDefault, is qualified.
+func (s *XsdSchema) attributeName(name, form string) string {
+	return s.localName(name, form, s.AttributeFormDefault)
+}
+
+func (s *XsdSchema) localName(name, form, formDefault string) string {
+	if form == "" {
+		form = formDefault
+	}
+	if form != "qualified" || s.TargetNamespace == "" {
+		return name
+	}
+	return s.TargetNamespace + " " + name
+}
+
+// Returns the namespace declarations among attrs, keyed by prefix.
+func namespaceDecls(attrs []xml.Attr) map[string]string {
+	xmlns := map[string]string{}
+	for _, attr := range attrs {
+		if attr.Name.Space == "xmlns" {
+			xmlns[attr.Name.Local] = attr.Value
+		} else if attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			xmlns[""] = attr.Value
+		}
+	}
+	return xmlns
+}
+
+// Resolves qname with the namespace declarations xmlns. Unprefixed names are
+// in the default namespace and names with an undeclared prefix are left
+// without namespace.
+func resolveQName(xmlns map[string]string, qname string) xml.Name {
+	prefix, local := "", qname
+	if i := strings.Index(qname, ":"); i >= 0 {
+		prefix, local = qname[:i], qname[i+1:]
+	}
+	if prefix == "xml" {
+		return xml.Name{Space: xmlNamespace, Local: local}
+	}
+	return xml.Name{Space: xmlns[prefix], Local: local}
 }
 
 type XsdInclude struct {
@@ -40,6 +124,7 @@ type XsdElement struct {
 	Nillable    bool            `xml:"nillable,attr"`
 	Type        string          `xml:"type,attr"`
 	Ref         string          `xml:"ref,attr"`
+	Form        string          `xml:"form,attr"`
 	MinOccurs   string          `xml:"minOccurs,attr"`
 	MaxOccurs   string          `xml:"maxOccurs,attr"`
 	ComplexType *XsdComplexType `xml:"complexType"` //local
@@ -61,11 +146,7 @@ type XsdComplexType struct {
 	Abstract       bool              `xml:"abstract,attr"`
 	Name           string            `xml:"name,attr"`
 	Mixed          bool              `xml:"mixed,attr"`
-	Sequence       []XsdElement      `xml:"sequence>element"`
-	SubSequence    []XsdElement      `xml:"sequence>sequence>element"`
-	Any			   []XsdAny		     `xml:"sequence>any"`
-	Choice         []XsdElement      `xml:"choice>element"`
-	All            []XsdElement      `xml:"all>element"`
+	XsdContentModel
 	ComplexContent XsdComplexContent `xml:"complexContent"`
 	SimpleContent  XsdSimpleContent  `xml:"simpleContent"`
 	SimpleType     *XsdSimpleType    `xml:"simpleType"`
@@ -73,25 +154,158 @@ type XsdComplexType struct {
 	AttributeGoups []*XsdAttributeGroup	`xml:"attributeGroup"`
 }
 
+// Returns the wildcards of the sequence of the content.
+func (t *XsdComplexType) Any() []*XsdAny {
+	var wildcards []*XsdAny
+	if t.Sequence != nil {
+		for _, p := range t.Sequence.Particles {
+			if p.Any != nil {
+				wildcards = append(wildcards, p.Any)
+			}
+		}
+	}
+	return wildcards
+}
+
+// Content model of a complex type, a derivation or a named group, ie. its
+// sequence, choice or all compositor.
+type XsdContentModel struct {
+	Sequence *XsdCompositor `xml:"sequence"`
+	Choice   *XsdCompositor `xml:"choice"`
+	All      *XsdCompositor `xml:"all"`
+	Group    *XsdGroup      `xml:"group"`
+}
+
+// Returns the compositor of the content model, or nil when it is empty. A
+// content model made of a group reference is returned as a sequence of it.
+func (m XsdContentModel) Content() *XsdCompositor {
+	switch {
+	case m.Sequence != nil:
+		return m.Sequence
+	case m.Choice != nil:
+		return m.Choice
+	case m.All != nil:
+		return m.All
+	case m.Group != nil:
+		return &XsdCompositor{Kind: "sequence", Particles: []*XsdParticle{{Group: m.Group}}}
+	}
+	return nil
+}
+
+// Compositor of a content model, ie. an xs:sequence, xs:choice or xs:all,
+// with its particles in document order.
+type XsdCompositor struct {
+	Kind      string // sequence, choice or all
+	MinOccurs string
+	MaxOccurs string
+	Particles []*XsdParticle
+}
+
+// Particle of a compositor, only one of its fields is set.
+type XsdParticle struct {
+	Element    *XsdElement
+	Compositor *XsdCompositor
+	Group      *XsdGroup
+	Any        *XsdAny
+}
+
+func (c *XsdCompositor) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	c.Kind = start.Name.Local
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "minOccurs":
+			c.MinOccurs = attr.Value
+		case "maxOccurs":
+			c.MaxOccurs = attr.Value
+		}
+	}
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			p := &XsdParticle{}
+			var v interface{}
+			switch token.Name.Local {
+			case "element":
+				p.Element = &XsdElement{}
+				v = p.Element
+			case "sequence", "choice", "all":
+				p.Compositor = &XsdCompositor{}
+				v = p.Compositor
+			case "group":
+				p.Group = &XsdGroup{}
+				v = p.Group
+			case "any":
+				p.Any = &XsdAny{}
+				v = p.Any
+			default:
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.DecodeElement(v, &token); err != nil {
+				return err
+			}
+			c.Particles = append(c.Particles, p)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// Returns the elements of the compositor and of the ones it nests, in
+// document order.
+func (c *XsdCompositor) elements() []*XsdElement {
+	if c == nil {
+		return nil
+	}
+	var elements []*XsdElement
+	for _, p := range c.Particles {
+		if p.Element != nil {
+			elements = append(elements, p.Element)
+		} else if p.Compositor != nil {
+			elements = append(elements, p.Compositor.elements()...)
+		}
+	}
+	return elements
+}
+
 type XsdAttributeGroup struct {
-	Name       string          `xml:"name,attr"`
-	Ref        string          `xml:"ref,attr"`
-	Doc        string          `xml:"annotation>documentation"`
-	Attributes []*XsdAttribute `xml:"attribute"`
+	Name            string               `xml:"name,attr"`
+	Ref             string               `xml:"ref,attr"`
+	Doc             string               `xml:"annotation>documentation"`
+	Attributes      []*XsdAttribute      `xml:"attribute"`
+	AttributeGroups []*XsdAttributeGroup `xml:"attributeGroup"`
 }
 
+// Named model group, or reference to one in a content model.
 type XsdGroup struct {
-	Name     string       `xml:"name,attr"`
-	Ref      string       `xml:"ref,attr"`
-	Sequence []XsdElement `xml:"sequence>element"`
-	Choice   []XsdElement `xml:"choice>element"`
-	All      []XsdElement `xml:"all>element"`
+	Name      string `xml:"name,attr"`
+	Ref       string `xml:"ref,attr"`
+	Doc       string `xml:"annotation>documentation"`
+	MinOccurs string `xml:"minOccurs,attr"`
+	MaxOccurs string `xml:"maxOccurs,attr"`
+	XsdContentModel
 }
 
 type XsdComplexContent struct {
 	XMLName   xml.Name     `xml:"complexContent"`
 	Doc        string      `xml:"annotation>documentation"`
 	Extension XsdExtension `xml:"extension"`
+	Restriction XsdComplexRestriction `xml:"restriction"`
+}
+
+// Restriction of a complex type, eg. of soapenc:Array by SOAP encoded arrays.
+type XsdComplexRestriction struct {
+	Base            string               `xml:"base,attr"`
+	Attributes      []*XsdAttribute      `xml:"attribute"`
+	AttributeGroups []*XsdAttributeGroup `xml:"attributeGroup"`
+	XsdContentModel
 }
 
 type XsdSimpleContent struct {
@@ -100,17 +314,23 @@ type XsdSimpleContent struct {
 }
 
 type XsdExtension struct {
-	XMLName    xml.Name        `xml:"extension"`
-	Base       string          `xml:"base,attr"`
-	Attributes []*XsdAttribute `xml:"attribute"`
-	Sequence   []XsdElement    `xml:"sequence>element"`
+	XMLName         xml.Name             `xml:"extension"`
+	Base            string               `xml:"base,attr"`
+	Attributes      []*XsdAttribute      `xml:"attribute"`
+	AttributeGroups []*XsdAttributeGroup `xml:"attributeGroup"`
+	XsdContentModel
 }
 
 type XsdAttribute struct {
 	Name       string         `xml:"name,attr"`
 	Doc        string         `xml:"annotation>documentation"`
 	Type       string         `xml:"type,attr"`
+	Ref        string         `xml:"ref,attr"`
+	Form       string         `xml:"form,attr"`
+	Use        string         `xml:"use,attr"`
 	SimpleType *XsdSimpleType `xml:"simpleType"`
+	// wsdl:arrayType of the soapenc:arrayType attribute of SOAP encoded arrays
+	ArrayType  string         `xml:"http://schemas.xmlsoap.org/wsdl/ arrayType,attr"`
 }
 
 type XsdSimpleType struct {
@@ -0,0 +1,145 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// TypeRegistry maps the qualified names of the complex types of a schema to
+// functions returning a pointer to a new value of their generated type.
+type TypeRegistry map[xml.Name]func() interface{}
+
+// Types registered by the generated packages, by qualified name, by local
+// name and by Go type. The first registration of a name wins.
+var xsiTypes = struct {
+	sync.RWMutex
+	names  TypeRegistry
+	locals map[string]func() interface{}
+	types  map[reflect.Type]xml.Name
+}{names: TypeRegistry{}, locals: map[string]func() interface{}{}, types: map[reflect.Type]xml.Name{}}
+
+// RegisterTypes registers the types of a schema which may be named by
+// xsi:type, ie. the abstract types, the types other types derive from and
+// the derived types. Generated packages register their types on init.
+func RegisterTypes(types TypeRegistry) {
+	xsiTypes.Lock()
+	defer xsiTypes.Unlock()
+	for name, newValue := range types {
+		if _, ok := xsiTypes.names[name]; !ok {
+			xsiTypes.names[name] = newValue
+		}
+		if _, ok := xsiTypes.locals[name.Local]; !ok {
+			xsiTypes.locals[name.Local] = newValue
+		}
+		if t := reflect.TypeOf(newValue()); t != nil {
+			if _, ok := xsiTypes.types[t]; !ok {
+				xsiTypes.types[t] = name
+			}
+		}
+	}
+}
+
+// Returns the function creating a value of the type registered as name, or,
+// when the namespace of name is unknown, the first one registered with its
+// local name.
+func lookupXsiType(name xml.Name, resolved bool) func() interface{} {
+	xsiTypes.RLock()
+	defer xsiTypes.RUnlock()
+	if newValue := xsiTypes.names[name]; newValue != nil || resolved {
+		return newValue
+	}
+	return xsiTypes.locals[name.Local]
+}
+
+// Returns the qualified name the Go type t is registered with, or else the
+// name of its element, for the types of the element of the same name, or of
+// the tag of its XMLName field.
+func xsiTypeName(t reflect.Type) (xml.Name, bool) {
+	xsiTypes.RLock()
+	name, ok := xsiTypes.types[t]
+	xsiTypes.RUnlock()
+	if ok {
+		return name, true
+	}
+	if t.Implements(reflect.TypeOf((*SoapElement)(nil)).Elem()) {
+		return reflect.Zero(t).Interface().(SoapElement).SoapElementName(), true
+	}
+	return xmlNameOf(t)
+}
+
+// MarshalXsiType encodes value, a pointer to a generated type, as the element
+// start annotated with the xsi:type of the type, the name it is registered
+// with. Nil values are not encoded.
+func MarshalXsiType(e *xml.Encoder, start xml.StartElement, value interface{}) error {
+	if isNilValue(value) {
+		return nil
+	}
+
+	if name, ok := xsiTypeName(reflect.TypeOf(value)); ok {
+		prefixes := &rpcPrefixes{names: map[string]string{}}
+		xsiType := prefixes.qname(name)
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:xsi"}, Value: XsiNamespace})
+		start.Attr = append(start.Attr, prefixes.attrs...)
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xsi:type"}, Value: xsiType})
+	}
+	return e.EncodeElement(value, start)
+}
+
+// UnmarshalXsiType decodes the element start into the registered type named
+// by its xsi:type and stores it in the interface pointed to by v. Elements
+// without xsi:type, or whose type is unknown or doesn't implement the
+// interface, are decoded into the value returned by newDefault.
+//
+// The prefix of the xsi:type is resolved with the namespaces declared on the
+// element itself, encoding/xml not exposing the other ones: types with
+// another prefix are looked up by local name.
+func UnmarshalXsiType(d *xml.Decoder, start xml.StartElement, v interface{}, newDefault func() interface{}) error {
+	target := reflect.ValueOf(v).Elem()
+
+	value := newDefault()
+	if name, resolved, ok := xsiTypeOf(start); ok {
+		if newValue := lookupXsiType(name, resolved); newValue != nil {
+			if derived := newValue(); reflect.TypeOf(derived).AssignableTo(target.Type()) {
+				value = derived
+			}
+		}
+	}
+
+	// The element is named after the field, not after the type.
+	if name, ok := xmlNameOf(reflect.TypeOf(value)); ok {
+		start.Name = name
+	}
+	if err := d.DecodeElement(value, &start); err != nil {
+		return err
+	}
+	target.Set(reflect.ValueOf(value))
+	return nil
+}
+
+// Returns the qualified name of the xsi:type of start, if any, and whether its
+// prefix was resolved.
+func xsiTypeOf(start xml.StartElement) (xml.Name, bool, bool) {
+	for _, attr := range start.Attr {
+		if attr.Name.Local != "type" || (attr.Name.Space != XsiNamespace && attr.Name.Space != "xsi") {
+			continue
+		}
+
+		prefix, local := "", strings.TrimSpace(attr.Value)
+		if i := strings.Index(local, ":"); i >= 0 {
+			prefix, local = local[:i], local[i+1:]
+		}
+		for _, ns := range start.Attr {
+			if (prefix == "" && ns.Name.Space == "" && ns.Name.Local == "xmlns") ||
+				(prefix != "" && ns.Name.Space == "xmlns" && ns.Name.Local == prefix) {
+				return xml.Name{Space: ns.Value, Local: local}, true, true
+			}
+		}
+		return xml.Name{Local: local}, false, true
+	}
+	return xml.Name{}, false, false
+}
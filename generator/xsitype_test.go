@@ -0,0 +1,158 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package generator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+// Written like the types generated for inheritance.wsdl.
+type shape struct {
+	Label string `xml:"http://example.com/drawing Label,omitempty"`
+}
+
+type shapeInterface interface {
+	isShape()
+}
+
+func (t *shape) isShape() {}
+
+type anyShape struct {
+	Value shapeInterface
+}
+
+func (a *anyShape) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return MarshalXsiType(e, start, a.Value)
+}
+
+func (a *anyShape) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return UnmarshalXsiType(d, start, &a.Value, func() interface{} { return new(shape) })
+}
+
+type circle struct {
+	shape
+	Radius float64 `xml:"http://example.com/drawing Radius,omitempty"`
+}
+
+type drawing struct {
+	XMLName xml.Name    `xml:"http://example.com/drawing Draw"`
+	Shapes  []*anyShape `xml:"http://example.com/drawing Shape,omitempty"`
+	Main    *anyShape   `xml:"http://example.com/drawing Main,omitempty"`
+	Origin  *circle     `xml:"http://example.com/drawing Origin,omitempty"`
+}
+
+func init() {
+	RegisterTypes(TypeRegistry{
+		{Space: "http://example.com/drawing", Local: "Shape"}:  func() interface{} { return new(shape) },
+		{Space: "http://example.com/drawing", Local: "Circle"}: func() interface{} { return new(circle) },
+		// Not a shapeInterface.
+		{Space: "http://example.com/drawing", Local: "Draw"}: func() interface{} { return new(drawing) },
+	})
+}
+
+func TestXsiTypeRoundTrip(t *testing.T) {
+	d := &drawing{
+		Shapes: []*anyShape{
+			{Value: &circle{shape: shape{Label: "c"}, Radius: 2}},
+			{Value: &shape{Label: "s"}},
+		},
+		Main:   &anyShape{Value: &circle{Radius: 3}},
+		Origin: &circle{Radius: 0.5},
+	}
+
+	data, err := xml.Marshal(d)
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	want := `<Draw xmlns="http://example.com/drawing">` +
+		`<Shape xmlns="http://example.com/drawing" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:ns1="http://example.com/drawing" xsi:type="ns1:Circle">` +
+		`<Label xmlns="http://example.com/drawing">c</Label><Radius xmlns="http://example.com/drawing">2</Radius></Shape>` +
+		`<Shape xmlns="http://example.com/drawing" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:ns1="http://example.com/drawing" xsi:type="ns1:Shape">` +
+		`<Label xmlns="http://example.com/drawing">s</Label></Shape>` +
+		`<Main xmlns="http://example.com/drawing" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:ns1="http://example.com/drawing" xsi:type="ns1:Circle">` +
+		`<Radius xmlns="http://example.com/drawing">3</Radius></Main>` +
+		`<Origin xmlns="http://example.com/drawing"><Radius xmlns="http://example.com/drawing">0.5</Radius></Origin></Draw>`
+	if string(data) != want {
+		t.Errorf("incorrect result\ngot:  %s\nwant: %s", data, want)
+	}
+
+	got := &drawing{}
+	if err := xml.Unmarshal(data, got); err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	wantShapes := []shapeInterface{
+		&circle{shape: shape{Label: "c"}, Radius: 2},
+		&shape{Label: "s"},
+	}
+	var gotShapes []shapeInterface
+	for _, s := range got.Shapes {
+		gotShapes = append(gotShapes, s.Value)
+	}
+	if !reflect.DeepEqual(gotShapes, wantShapes) {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", gotShapes, wantShapes)
+	}
+	if got.Main == nil || !reflect.DeepEqual(got.Main.Value, d.Main.Value) {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", got.Main, d.Main)
+	}
+	if !reflect.DeepEqual(got.Origin, d.Origin) {
+		t.Errorf("incorrect result\ngot:  %#v\nwant: %#v", got.Origin, d.Origin)
+	}
+}
+
+func TestUnmarshalXsiType(t *testing.T) {
+	tests := []struct {
+		content string
+		want    interface{}
+	}{
+		// prefix declared on the element
+		{`<Shape xmlns="http://example.com/drawing" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:d="http://example.com/drawing" xsi:type="d:Circle"/>`, &circle{}},
+		// prefix declared on an ancestor, looked up by local name
+		{`<Draw xmlns:d="http://example.com/drawing"><Shape xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="d:Circle"/></Draw>`, &circle{}},
+		// type of the default namespace
+		{`<Shape xmlns="http://example.com/drawing" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="Circle"/>`, &circle{}},
+		// type of another namespace
+		{`<Shape xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:o="http://example.com/other" xsi:type="o:Circle"/>`, &shape{}},
+		// type which doesn't derive from the base type
+		{`<Shape xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="Draw"/>`, &shape{}},
+		// no xsi:type
+		{`<Shape/>`, &shape{}},
+	}
+
+	for _, test := range tests {
+		d := xml.NewDecoder(bytes.NewReader([]byte(test.content)))
+		var start xml.StartElement
+		for {
+			token, err := d.Token()
+			if err != nil {
+				t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+			}
+			if el, ok := token.(xml.StartElement); ok && el.Name.Local == "Shape" {
+				start = el
+				break
+			}
+		}
+
+		a := &anyShape{}
+		if err := a.UnmarshalXML(d, start); err != nil {
+			t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+		}
+		if reflect.TypeOf(a.Value) != reflect.TypeOf(test.want) {
+			t.Errorf("incorrect result\ngot:  %T\nwant: %T", a.Value, test.want)
+		}
+	}
+}
+
+func TestMarshalXsiTypeNil(t *testing.T) {
+	data, err := xml.Marshal(&drawing{Shapes: []*anyShape{{}, {Value: (*circle)(nil)}}})
+	if err != nil {
+		t.Fatalf("incorrect result\ngot:  %#v\nwant: %#v", err, nil)
+	}
+	want := `<Draw xmlns="http://example.com/drawing"></Draw>`
+	if string(data) != want {
+		t.Errorf("incorrect result\ngot:  %s\nwant: %s", data, want)
+	}
+}